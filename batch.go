@@ -0,0 +1,185 @@
+package gos7logo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	gos7patch "github.com/axon-expert/gos7-logo-client/gos7-patch"
+)
+
+const (
+	// S7 allows at most 20 variables in one multi read/write job.
+	maxMultiItems = 20
+	// Telegram sizes of a multi read job, TPKT and COTP headers included,
+	// the way gos7patch checks them against the negotiated PDU length.
+	multiReadRequestHeader  = 19
+	multiReadRequestItem    = 12
+	multiReadResponseHeader = 21
+	multiReadResponseItem   = 4
+)
+
+// VmAddrResult is the outcome of reading one address of a batch. The
+// quality of the sample is derived from Err.
+type VmAddrResult struct {
+	Err error
+	Sample
+	VmAddr vmAddr
+}
+
+func (c *client) ReadMany(addrs ...vmAddr) ([]VmAddrResult, error) {
+	return c.ReadManyContext(context.Background(), addrs...)
+}
+
+// ReadManyContext reads addrs with as few multi read jobs as the negotiated
+// PDU length allows. Item errors are reported per result; the returned
+// error is set only when a whole job failed, its items carry it as well.
+func (c *client) ReadManyContext(ctx context.Context, addrs ...vmAddr) ([]VmAddrResult, error) {
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("failed `ReadMany`: addrs is empty")
+	}
+	results := make([]VmAddrResult, len(addrs))
+	pending := make([]int, 0, len(addrs))
+	for i, addr := range addrs {
+		results[i].VmAddr = addr
+		if err := c.model.Validate(addr); err != nil {
+			results[i].Err, results[i].Quality = err, QualityOutOfRange
+			continue
+		}
+		pending = append(pending, i)
+	}
+
+	plan := planReads(addrs, pending, gos7patch.PDULength(c.transport))
+	for n, job := range plan.Jobs {
+		bufs, spanErrs, err := c.readJob(ctx, job)
+		if err != nil {
+			for _, rest := range plan.Jobs[n:] {
+				for _, span := range rest.Spans {
+					for _, i := range span.Addrs {
+						results[i].Err, results[i].Quality = err, qualityOf(err)
+					}
+				}
+			}
+			return results, err
+		}
+		now := time.Now()
+		for k, span := range job.Spans {
+			for _, i := range span.Addrs {
+				results[i].Time = now
+				if spanErrs[k] != nil {
+					results[i].Err, results[i].Quality = spanErrs[k], qualityOf(spanErrs[k])
+					continue
+				}
+				offset := addrs[i].Byte - span.Start
+				results[i].Value, results[i].Err = c.getIntFromBuffer(addrs[i], bufs[k][offset:])
+				if results[i].Err != nil {
+					results[i].Quality = QualityConfigError
+				}
+			}
+		}
+	}
+
+	return results, nil
+}
+
+// readJob transfers the spans of job into one buffer per span. Errors the
+// PLC reports for single items of a multi read are returned per span.
+func (c *client) readJob(ctx context.Context, job PlanJob) ([][]byte, []error, error) {
+	bufs := make([][]byte, len(job.Spans))
+	spanErrs := make([]error, len(job.Spans))
+	if job.Contiguous() {
+		span := job.Spans[0]
+		bufs[0] = make([]byte, span.Size)
+		err := c.client.AGReadDBContext(ctx, c.dbNumber, int(span.Start), span.Size, bufs[0])
+		return bufs, spanErrs, err
+	}
+	items := make([]gos7patch.S7DataItem, len(job.Spans))
+	for k, span := range job.Spans {
+		items[k] = c.dataItem(span.Start, span.Size)
+	}
+	if err := c.client.AGReadMultiContext(ctx, items, len(items)); err != nil {
+		return nil, nil, err
+	}
+	for k, item := range items {
+		bufs[k] = item.Data
+		spanErrs[k] = item.Err
+	}
+	return bufs, spanErrs, nil
+}
+
+// WriteError reports the addresses of a WriteMany transfer that failed: all
+// addresses of the job when the PLC refused it or the connection broke,
+// the addresses of the item when the PLC rejected a single item.
+type WriteError struct {
+	Err   error
+	Addrs []vmAddr
+}
+
+func (e *WriteError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *WriteError) Unwrap() error {
+	return e.Err
+}
+
+// writeError wraps err with the addresses of spans.
+func writeError(err error, addrs []vmAddr, spans []PlanSpan) *WriteError {
+	wErr := &WriteError{Err: err}
+	for _, span := range spans {
+		for _, i := range span.Addrs {
+			wErr.Addrs = append(wErr.Addrs, addrs[i])
+		}
+	}
+	return wErr
+}
+
+// writeJob transfers one buffer per span of job. Bit spans hold the bit
+// value (0 or 1) in their single byte and are written with the S7 bit
+// transport size, leaving the other bits of the byte untouched.
+func (c *client) writeJob(ctx context.Context, job PlanJob, addrs []vmAddr, bufs [][]byte) error {
+	if job.Contiguous() {
+		span := job.Spans[0]
+		var err error
+		if span.Type == Bit {
+			err = c.client.AGWriteDBBitContext(ctx, c.dbNumber, int(span.Start), int(span.Bit), bufs[0][0] != 0)
+		} else {
+			err = c.client.AGWriteDBContext(ctx, c.dbNumber, int(span.Start), span.Size, bufs[0])
+		}
+		if err != nil {
+			return writeError(err, addrs, job.Spans)
+		}
+		return nil
+	}
+	items := make([]gos7patch.S7DataItem, len(job.Spans))
+	for k, span := range job.Spans {
+		items[k] = c.dataItem(span.Start, span.Size)
+		items[k].Data = bufs[k]
+		if span.Type == Bit {
+			items[k].WordLen = gos7patch.S7WLBit
+			items[k].Bit = int(span.Bit)
+		}
+	}
+	if err := c.client.AGWriteMultiContext(ctx, items, len(items)); err != nil {
+		return writeError(err, addrs, job.Spans)
+	}
+	for k, item := range items {
+		if item.Err != nil {
+			return writeError(fmt.Errorf("failed write %s: %w", job.Spans[k], item.Err), addrs, job.Spans[k:k+1])
+		}
+	}
+	return nil
+}
+
+// dataItem describes a byte range of the VM area for a multi read or write
+// job. Bits are read as their whole byte and extracted afterwards.
+func (c *client) dataItem(start uint32, size int) gos7patch.S7DataItem {
+	return gos7patch.S7DataItem{
+		Area:     gos7patch.S7AreaDB,
+		WordLen:  gos7patch.S7WLByte,
+		DBNumber: c.dbNumber,
+		Start:    int(start),
+		Amount:   size,
+		Data:     make([]byte, size),
+	}
+}
@@ -0,0 +1,202 @@
+package gos7logo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// FieldError reports the struct field ReadStruct or WriteStruct failed on.
+type FieldError struct {
+	Err   error
+	Field string
+	// Address of the field as given in its `logo` tag
+	Tag string
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field %s (`%s`): %s", e.Field, e.Tag, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// boundField is a struct field with a `logo` tag.
+type boundField struct {
+	name  string
+	tag   string
+	addr  vmAddr
+	index int
+}
+
+// ReadStruct fills the fields of the struct v points to from the VM
+// addresses in their `logo` tags, e.g.
+//
+//	type Pump struct {
+//		Running bool   `logo:"V10.0"`
+//		Speed   int16  `logo:"VW12"`
+//		Hours   uint32 `logo:"VD20"`
+//	}
+//
+// Tags hold VM addresses or LOGO! operands like `AI1`. Field types map to
+// data types like Tag does: bool, uint8, uint16, int16, uint32, int32 and
+// float32. All fields are read with one ReadMany, so nearby addresses
+// share transfers. The struct is only updated when all fields were read.
+func ReadStruct(ctx context.Context, c Client, v any) error {
+	value, fields, err := bindStruct(v)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	addrs := make([]vmAddr, len(fields))
+	for i, field := range fields {
+		addrs[i] = field.addr
+	}
+	results, err := c.ReadManyContext(ctx, addrs...)
+	for i, field := range fields {
+		if results == nil {
+			break
+		}
+		if results[i].Err != nil {
+			return &FieldError{Err: results[i].Err, Field: field.name, Tag: field.tag}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for i, field := range fields {
+		setField(value.Field(field.index), field.addr, results[i].Value)
+	}
+	return nil
+}
+
+// WriteStruct writes the fields of the struct v points to, see ReadStruct.
+// Only the bytes and bits of the tagged fields are written.
+func WriteStruct(ctx context.Context, c Client, v any) error {
+	value, fields, err := bindStruct(v)
+	if err != nil {
+		return err
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	args := make([]VmAddrValue, len(fields))
+	for i, field := range fields {
+		args[i] = VmAddrValue{VmAddr: field.addr, Value: fieldValue(value.Field(field.index))}
+	}
+	err = c.WriteManyContext(ctx, args...)
+	var failed []vmAddr
+	var rangeErr *AddressRangeError
+	var writeErr *WriteError
+	if errors.As(err, &rangeErr) {
+		failed = []vmAddr{rangeErr.Addr}
+	} else if errors.As(err, &writeErr) {
+		failed = writeErr.Addrs
+	}
+	for _, field := range fields {
+		for _, addr := range failed {
+			if field.addr.Byte == addr.Byte && field.addr.Type.Size() == addr.Type.Size() && field.addr.Bit == addr.Bit {
+				return &FieldError{Err: err, Field: field.name, Tag: field.tag}
+			}
+		}
+	}
+	return err
+}
+
+// bindStruct resolves the tagged fields of the struct v points to.
+func bindStruct(v any) (reflect.Value, []boundField, error) {
+	ptr := reflect.ValueOf(v)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() || ptr.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, nil, fmt.Errorf("expected pointer to struct, got %T", v)
+	}
+	value := ptr.Elem()
+	var fields []boundField
+	for i := range value.NumField() {
+		sf := value.Type().Field(i)
+		tag, ok := sf.Tag.Lookup("logo")
+		if !ok || tag == "-" {
+			continue
+		}
+		field := boundField{name: sf.Name, tag: tag, index: i}
+		if !sf.IsExported() {
+			return reflect.Value{}, nil, &FieldError{Err: errors.New("field is not exported"), Field: field.name, Tag: tag}
+		}
+		addr, err := NewVmAddrFromString(tag)
+		if err != nil {
+			if addr, err = NewVmAddrFromOperand(tag); err != nil {
+				return reflect.Value{}, nil, &FieldError{Err: err, Field: field.name, Tag: tag}
+			}
+		}
+		if field.addr, err = fieldAddr(sf.Type, addr); err != nil {
+			return reflect.Value{}, nil, &FieldError{Err: err, Field: field.name, Tag: tag}
+		}
+		fields = append(fields, field)
+	}
+	return value, fields, nil
+}
+
+// fieldAddr picks the data type of addr from the Go type of the field.
+func fieldAddr(t reflect.Type, addr vmAddr) (vmAddr, error) {
+	var want DataType
+	switch t.Kind() {
+	case reflect.Bool:
+		want = Bit
+	case reflect.Uint8:
+		want = Byte
+	case reflect.Uint16:
+		want = addr.Type
+		if want != Counter && want != Timer {
+			want = Word
+		}
+	case reflect.Int16:
+		want = Int16
+	case reflect.Uint32:
+		want = DWord
+	case reflect.Int32:
+		want = Int32
+	case reflect.Float32:
+		want = Float32
+	default:
+		return vmAddr{}, fmt.Errorf("unsupported field type %s", t)
+	}
+	if (want == Bit) != (addr.Type == Bit) || want.Size() != addr.Type.Size() {
+		return vmAddr{}, fmt.Errorf("field type %s does not fit address of type %v", t, addr.Type)
+	}
+	addr.Type = want
+	return addr, nil
+}
+
+func setField(f reflect.Value, addr vmAddr, raw uint32) {
+	switch addr.Type {
+	case Bit:
+		f.SetBool(raw != 0)
+	case Int16:
+		f.SetInt(int64(int16(raw)))
+	case Int32:
+		f.SetInt(int64(int32(raw)))
+	case Float32:
+		f.SetFloat(float64(math.Float32frombits(raw)))
+	default:
+		f.SetUint(uint64(raw))
+	}
+}
+
+func fieldValue(f reflect.Value) uint32 {
+	switch f.Kind() {
+	case reflect.Bool:
+		if f.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Int16, reflect.Int32:
+		return uint32(int32(f.Int()))
+	case reflect.Float32:
+		return math.Float32bits(float32(f.Float()))
+	}
+	return uint32(f.Uint())
+}
@@ -1,10 +1,11 @@
 package gos7logo
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"math"
 	"regexp"
-	"slices"
 	"strconv"
 	"strings"
 	"unicode"
@@ -22,15 +23,18 @@ const (
 	Timer
 	DWord
 	Real
+	Int16
+	Int32
+	Float32
 )
 
 func (t DataType) Size() int {
 	switch t {
 	case Bit, Byte:
 		return 1
-	case Word, Counter, Timer:
+	case Word, Counter, Timer, Int16:
 		return 2
-	case DWord, Real:
+	case DWord, Real, Int32, Float32:
 		return 4
 	default:
 		return 0
@@ -58,6 +62,10 @@ type vmAddr struct {
 	Bit  uint8
 }
 
+// VmAddr names the address type outside the package, e.g. to collect
+// addresses for ReadMany. Use the constructors to create values.
+type VmAddr = vmAddr
+
 func NewVmAddr(t DataType, byteAddr uint32, bit uint8) vmAddr {
 	return vmAddr{Type: t, Bit: bit, Byte: byteAddr}
 }
@@ -97,66 +105,139 @@ type VmAddrValue struct {
 }
 
 type Client interface {
+	// Read and Write carry the bit pattern of the value in the uint32:
+	// Int16 like Word, and Float32 as math.Float32bits. Real keeps its
+	// original meaning, Write converts the integer to a float and Read
+	// truncates the float; use Float32 or ReadFloat32 for fractions.
 	Read(addr vmAddr) (uint32, error)
 	Write(addr vmAddr, value uint32) error
 	WriteMany(addrs ...VmAddrValue) error
+	// ReadMany reads all addrs with S7 multi read jobs and reports values
+	// and errors per address, in the order of addrs.
+	ReadMany(addrs ...vmAddr) ([]VmAddrResult, error)
+	// Typed access to 2 and 4 byte addresses. The address type only has to
+	// match in size, so `VW12` can be read as int16 directly.
+	ReadInt16(addr vmAddr) (int16, error)
+	ReadInt32(addr vmAddr) (int32, error)
+	ReadFloat32(addr vmAddr) (float32, error)
+	WriteInt16(addr vmAddr, value int16) error
+	WriteInt32(addr vmAddr, value int32) error
+	WriteFloat32(addr vmAddr, value float32) error
+	// Context aware variants of the calls above. The context deadline is
+	// applied to the socket and cancellation aborts the exchange in flight.
+	ReadContext(ctx context.Context, addr vmAddr) (uint32, error)
+	WriteContext(ctx context.Context, addr vmAddr, value uint32) error
+	WriteManyContext(ctx context.Context, addrs ...VmAddrValue) error
+	ReadManyContext(ctx context.Context, addrs ...vmAddr) ([]VmAddrResult, error)
+	ReadInt16Context(ctx context.Context, addr vmAddr) (int16, error)
+	ReadInt32Context(ctx context.Context, addr vmAddr) (int32, error)
+	ReadFloat32Context(ctx context.Context, addr vmAddr) (float32, error)
+	WriteInt16Context(ctx context.Context, addr vmAddr, value int16) error
+	WriteInt32Context(ctx context.Context, addr vmAddr, value int32) error
+	WriteFloat32Context(ctx context.Context, addr vmAddr, value float32) error
+	Health() Health
+	SubscribeState(fn func(StateChange)) (unsubscribe func())
 	Disconnect() error
 }
 
 type client struct {
-	helper   gos7patch.Helper
-	client   gos7patch.Client
-	handler  *gos7patch.TCPClientHandler
-	area     string
-	dbNumber int
+	helper  gos7patch.Helper
+	client  gos7patch.Client
+	handler *gos7patch.TCPClientHandler
+	// transport the requests are sent with, handler unless configured
+	transport gos7patch.Transporter
+	area      string
+	dbNumber  int
+	model     Model
+	// requests go to a transport given with WithTransport, not to handler
+	external bool
 }
 
 func NewClient(addr string, rack int, slot int, snap7TSAP, logoTSAP uint16) (*client, error) {
-	handler := gos7patch.NewTCPClientHandlerWithTSAP(addr, rack, slot, snap7TSAP, logoTSAP)
-	if err := handler.Connect(); err != nil {
-		return nil, err
-	}
-	return &client{
-		area: "DB", dbNumber: 1,
-		client:  gos7patch.NewClient(handler),
-		handler: handler}, nil
+	return NewClientWithModel(addr, rack, slot, snap7TSAP, logoTSAP, DefaultModel)
+}
+
+// NewClientWithModel connects to a LOGO! of the given model. Addresses are
+// validated against the model's VM layout before any telegram is sent.
+func NewClientWithModel(addr string, rack int, slot int, snap7TSAP, logoTSAP uint16, model Model) (*client, error) {
+	return NewClientWithOpt(ConnectOpt{
+		Addr: addr, Rack: rack, Slot: slot,
+		LocalTSAP: snap7TSAP, RemoteTSAP: logoTSAP,
+		Model: model,
+	})
+}
+
+// Model returns the LOGO! model the client was created for.
+func (c *client) Model() Model {
+	return c.model
 }
 
 func (c *client) Write(addr vmAddr, value uint32) error {
-	size := addr.Type.Size()
-	buff := make([]byte, size)
+	return c.WriteContext(context.Background(), addr, value)
+}
+
+// WriteContext writes value to addr. Bits are written with a bit level S7
+// write, so the other bits of the byte are never touched.
+func (c *client) WriteContext(ctx context.Context, addr vmAddr, value uint32) error {
+	if err := c.model.Validate(addr); err != nil {
+		return err
+	}
 	if addr.Type == Bit {
-		if err := c.client.AGReadDB(c.dbNumber, int(addr.Byte), size, buff); err != nil {
-			return err
-		}
+		return c.client.AGWriteDBBitContext(ctx, c.dbNumber, int(addr.Byte), int(addr.Bit), value > 0)
 	}
+	size := addr.Type.Size()
+	buff := make([]byte, size)
 	if err := c.writeToBuffer(addr, buff, value); err != nil {
 		return err
 	}
-	if err := c.client.AGWriteDB(c.dbNumber, int(addr.Byte), size, buff); err != nil {
+	if err := c.client.AGWriteDBContext(ctx, c.dbNumber, int(addr.Byte), size, buff); err != nil {
 		return err
 	}
 	return nil
 }
+
 func (c *client) WriteMany(args ...VmAddrValue) error {
+	return c.WriteManyContext(context.Background(), args...)
+}
+
+// WriteManyContext writes args without reading anything back first: only
+// the bytes and bits of args are transferred, so values the LOGO! program
+// changes next to them are never overwritten with stale data. Where args
+// overlap, the later one wins.
+func (c *client) WriteManyContext(ctx context.Context, args ...VmAddrValue) error {
 	if len(args) == 0 {
 		return fmt.Errorf("failed `WriteMany`: args is empty")
 	}
-	minByte := slices.MinFunc(args, compareVmAddrByte)
-	maxByte := slices.MaxFunc(args, compareVmAddrByte)
-	size := int(maxByte.VmAddr.Byte-minByte.VmAddr.Byte) + 1
-	buff := make([]byte, size)
-	if err := c.client.AGReadDB(c.dbNumber, int(minByte.VmAddr.Byte), size, buff); err != nil {
-		return err
-	}
 	for _, val := range args {
-		offset := int(val.VmAddr.Byte - minByte.VmAddr.Byte)
-		if err := c.writeToBuffer(val.VmAddr, buff[offset:], val.Value); err != nil {
+		if err := c.model.Validate(val.VmAddr); err != nil {
 			return err
 		}
 	}
-	if err := c.client.AGWriteDB(c.dbNumber, int(minByte.VmAddr.Byte), size, buff); err != nil {
-		return err
+	addrs := make([]vmAddr, len(args))
+	for i, val := range args {
+		addrs[i] = val.VmAddr
+	}
+	plan := planWrites(addrs, gos7patch.PDULength(c.transport))
+	for _, job := range plan.Jobs {
+		bufs := make([][]byte, len(job.Spans))
+		for k, span := range job.Spans {
+			bufs[k] = make([]byte, span.Size)
+			for _, i := range span.Addrs {
+				if span.Type == Bit {
+					if args[i].Value > 0 {
+						bufs[k][0] = 1
+					}
+					continue
+				}
+				offset := addrs[i].Byte - span.Start
+				if err := c.writeToBuffer(addrs[i], bufs[k][offset:], args[i].Value); err != nil {
+					return err
+				}
+			}
+		}
+		if err := c.writeJob(ctx, job, addrs, bufs); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -171,11 +252,13 @@ func (c *client) writeToBuffer(addr vmAddr, buff []byte, value uint32) error {
 		}
 	case Byte:
 		c.helper.SetValueAt(buff, 0, uint8(value))
-	case DWord:
+	case DWord, Int32:
 		c.helper.SetValueAt(buff, 0, uint32(value))
 	case Real:
 		c.helper.SetValueAt(buff, 0, float32(value))
-	case Word, Counter, Timer:
+	case Float32:
+		c.helper.SetValueAt(buff, 0, math.Float32frombits(value))
+	case Word, Counter, Timer, Int16:
 		c.helper.SetValueAt(buff, 0, uint16(value))
 	default:
 		return errors.New("write: unknown data type")
@@ -185,9 +268,12 @@ func (c *client) writeToBuffer(addr vmAddr, buff []byte, value uint32) error {
 }
 
 func (c *client) Read(addr vmAddr) (uint32, error) {
-	size := addr.Type.Size()
-	buff := make([]byte, size)
-	if err := c.client.AGReadDB(c.dbNumber, int(addr.Byte), size, buff); err != nil {
+	return c.ReadContext(context.Background(), addr)
+}
+
+func (c *client) ReadContext(ctx context.Context, addr vmAddr) (uint32, error) {
+	buff, err := c.readSized(ctx, addr, addr.Type.Size())
+	if err != nil {
 		return 0, err
 	}
 	result, err := c.getIntFromBuffer(addr, buff)
@@ -210,11 +296,11 @@ func (c *client) getIntFromBuffer(addr vmAddr, buff []byte) (uint32, error) {
 		var result uint8
 		c.helper.GetValueAt(buff, 0, &result)
 		return uint32(result), nil
-	case Word, Counter, Timer:
+	case Word, Counter, Timer, Int16:
 		var result uint16
 		c.helper.GetValueAt(buff, 0, &result)
 		return uint32(result), nil
-	case DWord:
+	case DWord, Int32:
 		var result uint32
 		c.helper.GetValueAt(buff, 0, &result)
 		return uint32(result), nil
@@ -222,11 +308,117 @@ func (c *client) getIntFromBuffer(addr vmAddr, buff []byte) (uint32, error) {
 		var result float32
 		c.helper.GetValueAt(buff, 0, &result)
 		return uint32(result), nil
+	case Float32:
+		var result float32
+		c.helper.GetValueAt(buff, 0, &result)
+		return math.Float32bits(result), nil
 	}
 
 	return 0, errors.New("write: unknown data type")
 }
 
+func (c *client) ReadInt16(addr vmAddr) (int16, error) {
+	return c.ReadInt16Context(context.Background(), addr)
+}
+
+func (c *client) ReadInt16Context(ctx context.Context, addr vmAddr) (int16, error) {
+	buff, err := c.readSized(ctx, addr, 2)
+	if err != nil {
+		return 0, err
+	}
+	var result int16
+	c.helper.GetValueAt(buff, 0, &result)
+	return result, nil
+}
+
+func (c *client) ReadInt32(addr vmAddr) (int32, error) {
+	return c.ReadInt32Context(context.Background(), addr)
+}
+
+func (c *client) ReadInt32Context(ctx context.Context, addr vmAddr) (int32, error) {
+	buff, err := c.readSized(ctx, addr, 4)
+	if err != nil {
+		return 0, err
+	}
+	var result int32
+	c.helper.GetValueAt(buff, 0, &result)
+	return result, nil
+}
+
+func (c *client) ReadFloat32(addr vmAddr) (float32, error) {
+	return c.ReadFloat32Context(context.Background(), addr)
+}
+
+func (c *client) ReadFloat32Context(ctx context.Context, addr vmAddr) (float32, error) {
+	buff, err := c.readSized(ctx, addr, 4)
+	if err != nil {
+		return 0, err
+	}
+	return c.helper.GetRealAt(buff, 0), nil
+}
+
+func (c *client) WriteInt16(addr vmAddr, value int16) error {
+	return c.WriteInt16Context(context.Background(), addr, value)
+}
+
+func (c *client) WriteInt16Context(ctx context.Context, addr vmAddr, value int16) error {
+	buff := make([]byte, 2)
+	c.helper.SetValueAt(buff, 0, value)
+	return c.writeSized(ctx, addr, buff)
+}
+
+func (c *client) WriteInt32(addr vmAddr, value int32) error {
+	return c.WriteInt32Context(context.Background(), addr, value)
+}
+
+func (c *client) WriteInt32Context(ctx context.Context, addr vmAddr, value int32) error {
+	buff := make([]byte, 4)
+	c.helper.SetValueAt(buff, 0, value)
+	return c.writeSized(ctx, addr, buff)
+}
+
+func (c *client) WriteFloat32(addr vmAddr, value float32) error {
+	return c.WriteFloat32Context(context.Background(), addr, value)
+}
+
+func (c *client) WriteFloat32Context(ctx context.Context, addr vmAddr, value float32) error {
+	buff := make([]byte, 4)
+	c.helper.SetRealAt(buff, 0, value)
+	return c.writeSized(ctx, addr, buff)
+}
+
+// readSized reads size bytes at addr after checking that the address type
+// holds a value of that size.
+func (c *client) readSized(ctx context.Context, addr vmAddr, size int) ([]byte, error) {
+	if err := c.checkSize(addr, size); err != nil {
+		return nil, err
+	}
+	buff := make([]byte, size)
+	if err := c.client.AGReadDBContext(ctx, c.dbNumber, int(addr.Byte), size, buff); err != nil {
+		return nil, err
+	}
+	return buff, nil
+}
+
+// writeSized writes the encoded value in buff to addr after checking that
+// the address type holds a value of that size.
+func (c *client) writeSized(ctx context.Context, addr vmAddr, buff []byte) error {
+	if err := c.checkSize(addr, len(buff)); err != nil {
+		return err
+	}
+	return c.client.AGWriteDBContext(ctx, c.dbNumber, int(addr.Byte), len(buff), buff)
+}
+
+func (c *client) checkSize(addr vmAddr, size int) error {
+	if err := c.model.Validate(addr); err != nil {
+		return err
+	}
+	if addr.Type.Size() != size {
+		return fmt.Errorf("address of type %v does not hold a %d byte value", addr.Type, size)
+	}
+	return nil
+}
+
 func (c *client) Disconnect() error {
 	return c.handler.Close()
 }
@@ -0,0 +1,349 @@
+// Command gos7logo-gen generates typed accessors for the tags of a tag file
+// (see gos7logo.LoadRegistry), to be used with go generate:
+//
+//	//go:generate go run github.com/axon-expert/gos7-logo-client/cmd/gos7logo-gen -in tags.yaml -out tags_gen.go -pkg plant
+//
+// The generated package has a type with a getter and, unless the tag is
+// read-only, a setter per tag, and a Snapshot method that reads all tags
+// with one batch read. Tags with a scaling are accessed as float64 in
+// engineering units.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"unicode"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+const accessorsTemplate = `// Code generated by gos7logo-gen from {{.Source}}. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+	"context"
+{{- if .HasFloat}}
+	"math"
+{{- end}}
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+// {{.Type}} gives typed access to the tags of {{.Source}}.
+type {{.Type}} struct {
+	client gos7logo.Client
+}
+
+func New{{.Type}}(c gos7logo.Client) *{{.Type}} {
+	return &{{.Type}}{client: c}
+}
+
+// {{.Type}}Snapshot holds the values of all tags read at once.
+type {{.Type}}Snapshot struct {
+{{- range .Tags}}
+	{{.Ident}} {{.GoType}}
+{{- end}}
+}
+{{range .Tags}}
+// {{.Ident}} reads {{.Addr}}{{if .Unit}} in {{.Unit}}{{end}}{{if .Description}}: {{.Description}}{{end}}.
+func (p *{{$.Type}}) {{.Ident}}(ctx context.Context) ({{.GoType}}, error) {
+	{{- if .Scaling}}
+	return gos7logo.ScaledAddr{Addr: {{.AddrExpr}}, Scaling: {{.Scaling}}}.Read(ctx, p.client)
+	{{- else if eq .GoType "bool"}}
+	v, err := p.client.ReadContext(ctx, {{.AddrExpr}})
+	return v != 0, err
+	{{- else if eq .GoType "int16"}}
+	return p.client.ReadInt16Context(ctx, {{.AddrExpr}})
+	{{- else if eq .GoType "int32"}}
+	return p.client.ReadInt32Context(ctx, {{.AddrExpr}})
+	{{- else if eq .GoType "float32"}}
+	return p.client.ReadFloat32Context(ctx, {{.AddrExpr}})
+	{{- else if eq .GoType "uint32"}}
+	return p.client.ReadContext(ctx, {{.AddrExpr}})
+	{{- else}}
+	v, err := p.client.ReadContext(ctx, {{.AddrExpr}})
+	return {{.GoType}}(v), err
+	{{- end}}
+}
+{{if not .ReadOnly}}
+// Set{{.Ident}} writes {{.Addr}}{{if .Unit}} in {{.Unit}}{{end}}.
+func (p *{{$.Type}}) Set{{.Ident}}(ctx context.Context, value {{.GoType}}) error {
+	{{- if .Scaling}}
+	return gos7logo.ScaledAddr{Addr: {{.AddrExpr}}, Scaling: {{.Scaling}}}.Write(ctx, p.client, value)
+	{{- else if eq .GoType "bool"}}
+	var v uint32
+	if value {
+		v = 1
+	}
+	return p.client.WriteContext(ctx, {{.AddrExpr}}, v)
+	{{- else if eq .GoType "int16"}}
+	return p.client.WriteInt16Context(ctx, {{.AddrExpr}}, value)
+	{{- else if eq .GoType "int32"}}
+	return p.client.WriteInt32Context(ctx, {{.AddrExpr}}, value)
+	{{- else if eq .GoType "float32"}}
+	return p.client.WriteFloat32Context(ctx, {{.AddrExpr}}, value)
+	{{- else if eq .GoType "uint32"}}
+	return p.client.WriteContext(ctx, {{.AddrExpr}}, value)
+	{{- else}}
+	return p.client.WriteContext(ctx, {{.AddrExpr}}, uint32(value))
+	{{- end}}
+}
+{{end}}
+{{- end}}
+// Snapshot reads all tags with one batch read.
+func (p *{{.Type}}) Snapshot(ctx context.Context) ({{.Type}}Snapshot, error) {
+	var s {{.Type}}Snapshot
+	results, err := p.client.ReadManyContext(ctx,
+{{- range .Tags}}
+		{{.AddrExpr}},
+{{- end}}
+	)
+	if err != nil {
+		return s, err
+	}
+	for _, res := range results {
+		if res.Err != nil {
+			return s, res.Err
+		}
+	}
+{{- range .Tags}}
+	s.{{.Ident}} = {{.SnapshotExpr}}
+{{- end}}
+	return s, nil
+}
+`
+
+type genFile struct {
+	Source   string
+	Package  string
+	Type     string
+	Tags     []genTag
+	HasFloat bool
+}
+
+type genTag struct {
+	Ident       string
+	Addr        string
+	Description string
+	GoType      string
+	AddrExpr    string
+	// Scaling literal of scaled tags, empty for raw values
+	Scaling      string
+	Unit         string
+	SnapshotExpr string
+	ReadOnly     bool
+}
+
+func main() {
+	in := flag.String("in", "", "tag file, YAML or JSON")
+	out := flag.String("out", "", "generated Go file, stdout when empty")
+	pkg := flag.String("pkg", "", "package name of the generated file, default is the directory name of -out")
+	typeName := flag.String("type", "Program", "name of the generated type")
+	flag.Parse()
+	if *in == "" {
+		log.Fatal("gos7logo-gen: -in is required")
+	}
+	if *pkg == "" {
+		if *out == "" {
+			log.Fatal("gos7logo-gen: -pkg is required when writing to stdout")
+		}
+		abs, err := filepath.Abs(*out)
+		if err != nil {
+			log.Fatalf("gos7logo-gen: %s", err)
+		}
+		*pkg = filepath.Base(filepath.Dir(abs))
+	}
+
+	registry, err := gos7logo.LoadRegistry(*in)
+	if err != nil {
+		log.Fatalf("gos7logo-gen: %s", err)
+	}
+	code, err := generate(registry, filepath.Base(*in), *pkg, *typeName)
+	if err != nil {
+		log.Fatalf("gos7logo-gen: %s", err)
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(code)
+	} else {
+		err = os.WriteFile(*out, code, 0o644)
+	}
+	if err != nil {
+		log.Fatalf("gos7logo-gen: %s", err)
+	}
+}
+
+// generate renders the accessors of all tags of registry.
+func generate(registry *gos7logo.Registry, source, pkg, typeName string) ([]byte, error) {
+	file := genFile{Source: source, Package: pkg, Type: typeName}
+	// identifiers of the generated code, mapped to the tag they belong to
+	idents := map[string]string{
+		typeName:              "",
+		"New" + typeName:      "",
+		typeName + "Snapshot": "",
+		"Snapshot":            "",
+	}
+	for i, name := range registry.Names() {
+		def, err := registry.Def(name)
+		if err != nil {
+			return nil, err
+		}
+		addr, err := registry.Addr(name)
+		if err != nil {
+			return nil, err
+		}
+		ident := goIdent(name)
+		if ident == "" {
+			return nil, fmt.Errorf("tag `%s` has no usable identifier", name)
+		}
+		generated := []string{ident}
+		if !def.ReadOnly {
+			generated = append(generated, "Set"+ident)
+		}
+		for _, id := range generated {
+			other, ok := idents[id]
+			if ok && other == "" {
+				return nil, fmt.Errorf("tag `%s` maps to %s, which is generated for the %s type", name, id, typeName)
+			}
+			if ok {
+				return nil, fmt.Errorf("tags `%s` and `%s` both map to %s", other, name, id)
+			}
+			idents[id] = name
+		}
+		goType, dataType := goTypeOf(addr.Type)
+		tag := genTag{
+			Ident:        ident,
+			Addr:         def.Addr,
+			Description:  strings.Join(strings.Fields(def.Description), " "),
+			GoType:       goType,
+			AddrExpr:     addrExpr(dataType, addr),
+			SnapshotExpr: valueExpr(goType, fmt.Sprintf("results[%d].Value", i)),
+			ReadOnly:     def.ReadOnly,
+		}
+		if def.Scaling != nil {
+			if addr.Type == gos7logo.Bit {
+				return nil, fmt.Errorf("tag `%s`: bit addresses cannot be scaled", name)
+			}
+			tag.Scaling = scalingExpr(*def.Scaling)
+			tag.Unit = def.Scaling.Unit
+			tag.SnapshotExpr = fmt.Sprintf("%s.ToEng(float64(%s))", tag.Scaling, tag.SnapshotExpr)
+			tag.GoType = "float64"
+		}
+		if goType == "float32" {
+			file.HasFloat = true
+		}
+		file.Tags = append(file.Tags, tag)
+	}
+	if len(file.Tags) == 0 {
+		return nil, fmt.Errorf("no tags defined")
+	}
+
+	tmpl, err := template.New("accessors").Parse(accessorsTemplate)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, file); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
+
+func addrExpr(dataType string, addr gos7logo.VmAddr) string {
+	return fmt.Sprintf("gos7logo.NewVmAddr(gos7logo.%s, %d, %d)", dataType, addr.Byte, addr.Bit)
+}
+
+// valueExpr converts the uint32 value expression raw of a batch read to
+// goType.
+func valueExpr(goType, raw string) string {
+	switch goType {
+	case "bool":
+		return raw + " != 0"
+	case "float32":
+		return "math.Float32frombits(" + raw + ")"
+	case "uint32":
+		return raw
+	}
+	return goType + "(" + raw + ")"
+}
+
+// scalingExpr returns a gos7logo.Scaling literal of s.
+func scalingExpr(s gos7logo.Scaling) string {
+	var fields []string
+	if s.Unit != "" {
+		fields = append(fields, fmt.Sprintf("Unit: %q", s.Unit))
+	}
+	if len(s.Points) > 0 {
+		points := make([]string, len(s.Points))
+		for i, p := range s.Points {
+			points[i] = fmt.Sprintf("{Raw: %s, Eng: %s}", floatLit(p.Raw), floatLit(p.Eng))
+		}
+		fields = append(fields, "Points: []gos7logo.ScalePoint{"+strings.Join(points, ", ")+"}")
+	} else {
+		fields = append(fields,
+			"RawMin: "+floatLit(s.RawMin), "RawMax: "+floatLit(s.RawMax),
+			"EngMin: "+floatLit(s.EngMin), "EngMax: "+floatLit(s.EngMax))
+	}
+	if s.Clamp {
+		fields = append(fields, "Clamp: true")
+	}
+	return "gos7logo.Scaling{" + strings.Join(fields, ", ") + "}"
+}
+
+func floatLit(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// goTypeOf returns the Go type and the name of the gos7logo constant of t.
+func goTypeOf(t gos7logo.DataType) (string, string) {
+	switch t {
+	case gos7logo.Bit:
+		return "bool", "Bit"
+	case gos7logo.Byte:
+		return "uint8", "Byte"
+	case gos7logo.Word:
+		return "uint16", "Word"
+	case gos7logo.Counter:
+		return "uint16", "Counter"
+	case gos7logo.Timer:
+		return "uint16", "Timer"
+	case gos7logo.DWord:
+		return "uint32", "DWord"
+	case gos7logo.Int16:
+		return "int16", "Int16"
+	case gos7logo.Int32:
+		return "int32", "Int32"
+	}
+	// Real values are read as Float32, which batch reads return as bits
+	return "float32", "Float32"
+}
+
+// goIdent turns a tag name like `boiler_temp` or `B001_current_value` into
+// an exported identifier like `BoilerTemp` or `B001CurrentValue`.
+func goIdent(name string) string {
+	var sb strings.Builder
+	upper := true
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if sb.Len() == 0 && unicode.IsDigit(r) {
+			sb.WriteString("Tag")
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
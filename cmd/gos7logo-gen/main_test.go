@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files")
+
+func TestGenerateGolden(t *testing.T) {
+	registry, err := gos7logo.LoadRegistry(filepath.Join("testdata", "tags.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := generate(registry, "tags.yaml", "plant", "Program")
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "tags_gen.go.golden")
+	if *update {
+		if err := os.WriteFile(golden, code, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, want) {
+		t.Errorf("generated code differs from %s, run go test -update to accept:\n%s", golden, code)
+	}
+
+	// the generated package builds against the library of this module
+	dir, err := os.MkdirTemp(".", "plant")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	if err := os.WriteFile(filepath.Join(dir, "tags_gen.go"), code, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if out, err := exec.Command("go", "build", "./"+dir).CombinedOutput(); err != nil {
+		t.Errorf("generated code does not compile: %v\n%s", err, out)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	cases := []struct {
+		name string
+		tags string
+		err  string
+	}{
+		{"tag collision", "{name: a_b, addr: V1}, {name: a-b, addr: V2}", "both map to AB"},
+		{"setter collision", "{name: speed, addr: V1}, {name: set_speed, addr: V2}", "both map to SetSpeed"},
+		{"snapshot method", "{name: snapshot, addr: V1}", "maps to Snapshot"},
+		{"constructor", "{name: new_program, addr: V1}", "maps to NewProgram"},
+		{"snapshot type", "{name: program_snapshot, addr: V1}", "maps to ProgramSnapshot"},
+		{"scaled bit", "{name: run, addr: V1.0, scaling: {rawMax: 1, engMax: 10}}", "cannot be scaled"},
+	}
+	for _, tc := range cases {
+		registry, err := gos7logo.ParseRegistryYAML([]byte("tags: [" + tc.tags + "]"))
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if _, err := generate(registry, "tags.yaml", "plant", "Program"); err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("%s: expected error %q, got %v", tc.name, tc.err, err)
+		}
+	}
+}
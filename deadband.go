@@ -0,0 +1,112 @@
+package gos7logo
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// defaultAnalogSpan is the range of the LOGO! analog values (0..1000) the
+// percentage deadband refers to unless Span is set.
+const defaultAnalogSpan = 1000
+
+// Deadband decides which analog readings are worth publishing. A value is
+// published when it moved away from the last published one by more than
+// Absolute or Percent of Span, but not sooner than MinInterval after the
+// previous publish. A value unchanged for MaxSilence is published again.
+// Zero fields disable the respective rule.
+type Deadband struct {
+	// Threshold in raw counts
+	Absolute float64
+	// Threshold in percent of Span
+	Percent float64
+	// Range the percentage refers to, defaultAnalogSpan when not set
+	Span        float64
+	MinInterval time.Duration
+	MaxSilence  time.Duration
+}
+
+func (d Deadband) threshold() float64 {
+	span := d.Span
+	if span <= 0 {
+		span = defaultAnalogSpan
+	}
+	return max(d.Absolute, d.Percent/100*span)
+}
+
+// deadbandState holds the last published value of one address.
+type deadbandState struct {
+	published time.Time
+	config    Deadband
+	last      float64
+	hasValue  bool
+}
+
+func (s *deadbandState) update(value float64, now time.Time) bool {
+	if s.hasValue {
+		since := now.Sub(s.published)
+		if s.config.MinInterval > 0 && since < s.config.MinInterval {
+			return false
+		}
+		silent := s.config.MaxSilence > 0 && since >= s.config.MaxSilence
+		if !silent && math.Abs(value-s.last) <= s.config.threshold() {
+			return false
+		}
+	}
+	s.last, s.published, s.hasValue = value, now, true
+	return true
+}
+
+// analogValue interprets a raw value according to the type of addr, see
+// Client.Read.
+func analogValue(addr vmAddr, value uint32) float64 {
+	switch addr.Type {
+	case Int16:
+		return float64(int16(value))
+	case Int32:
+		return float64(int32(value))
+	case Float32:
+		return float64(math.Float32frombits(value))
+	}
+	return float64(value)
+}
+
+// DeadbandFilter applies per address deadbands to repeated reads, so that
+// jittering analog inputs only produce meaningful changes. Feed it every
+// reading, not only changed ones, for MaxSilence to work.
+type DeadbandFilter struct {
+	states map[vmAddr]*deadbandState
+	mu     sync.Mutex
+}
+
+func NewDeadbandFilter() *DeadbandFilter {
+	return &DeadbandFilter{states: make(map[vmAddr]*deadbandState)}
+}
+
+// Set configures the deadband of addr and forgets its last published value.
+func (f *DeadbandFilter) Set(addr vmAddr, d Deadband) error {
+	if addr.Type == Bit {
+		return fmt.Errorf("failed `Set`: deadband on bit address V%d.%d", addr.Byte, addr.Bit)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.states[addr] = &deadbandState{config: d}
+	return nil
+}
+
+// Update reports whether value read from addr at now should be published.
+// Addresses without a deadband publish every change.
+func (f *DeadbandFilter) Update(addr vmAddr, value uint32, now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	state, ok := f.states[addr]
+	if !ok {
+		state = &deadbandState{}
+		f.states[addr] = state
+	}
+	return state.update(analogValue(addr, value), now)
+}
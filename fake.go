@@ -0,0 +1,466 @@
+package gos7logo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	gos7patch "github.com/axon-expert/gos7-logo-client/gos7-patch"
+)
+
+// FakeWrite is a write recorded by FakeClient.
+type FakeWrite struct {
+	// Bytes as they were stored in the VM image, the bit value (0 or 1)
+	// for bit addresses
+	Data   []byte
+	VmAddr vmAddr
+	// Value passed to Write or WriteMany. Typed writes record the two's
+	// complement of integers and the IEEE 754 bits of floats.
+	Value uint32
+	// Number of the client call the write belongs to, counted from 1. The
+	// writes of one WriteMany share it.
+	Call int
+}
+
+// FakeClient is an in-memory Client for unit tests of code using the
+// library. It keeps a VM image encoded exactly like the LOGO! holds it,
+// validates addresses against its model like the real client does and
+// records every write. Errors can be injected per address, connection
+// states with SetState.
+type FakeClient struct {
+	lastErrorTime time.Time
+	lastError     error
+	readErrs      map[vmAddr]error
+	writeErrs     map[vmAddr]error
+	subscribers   map[int]func(StateChange)
+	image         []byte
+	writes        []FakeWrite
+	codec         client // encodes values the way the real client does
+	calls         int
+	nextSub       int
+	model         Model
+	state         ConnState
+	mu            sync.Mutex
+}
+
+// NewFakeClient returns a connected fake with a zeroed VM image.
+func NewFakeClient(model Model) *FakeClient {
+	return &FakeClient{
+		codec:       client{model: model},
+		readErrs:    map[vmAddr]error{},
+		writeErrs:   map[vmAddr]error{},
+		subscribers: map[int]func(StateChange){},
+		model:       model,
+		state:       StateConnected,
+	}
+}
+
+// FailRead makes reads of addresses overlapping addr fail with err, until
+// ClearErrors is called. Errors of bit addresses only hit that bit.
+func (f *FakeClient) FailRead(addr vmAddr, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.readErrs[addr] = err
+}
+
+// FailWrite makes writes to addresses overlapping addr fail with err,
+// see FailRead. A failing WriteMany writes nothing.
+func (f *FakeClient) FailWrite(addr vmAddr, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writeErrs[addr] = err
+}
+
+// ClearErrors removes all injected errors.
+func (f *FakeClient) ClearErrors() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	clear(f.readErrs)
+	clear(f.writeErrs)
+}
+
+// Set stores value at addr without recording a write, e.g. to preset the
+// values the LOGO! program would produce.
+func (f *FakeClient) Set(addr vmAddr, value uint32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.model.Validate(addr); err != nil {
+		return err
+	}
+	return f.codec.writeToBuffer(addr, f.bytes(addr.Byte, addr.Type.Size()), value)
+}
+
+// Get returns the value at addr, like Read but without injected errors.
+func (f *FakeClient) Get(addr vmAddr) (uint32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.model.Validate(addr); err != nil {
+		return 0, err
+	}
+	return f.codec.getIntFromBuffer(addr, f.bytes(addr.Byte, addr.Type.Size()))
+}
+
+// SetBytes copies data into the VM image at start without recording a write.
+func (f *FakeClient) SetBytes(start uint32, data []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copy(f.bytes(start, len(data)), data)
+}
+
+// Bytes returns a copy of n bytes of the VM image from start on.
+func (f *FakeClient) Bytes(start uint32, n int) []byte {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]byte(nil), f.bytes(start, n)...)
+}
+
+// Writes returns the recorded writes in the order they happened.
+func (f *FakeClient) Writes() []FakeWrite {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeWrite(nil), f.writes...)
+}
+
+// ResetWrites forgets the recorded writes.
+func (f *FakeClient) ResetWrites() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writes = nil
+}
+
+// bytes returns the image slice at start, growing the image as needed.
+// Caller must hold the mutex.
+func (f *FakeClient) bytes(start uint32, n int) []byte {
+	end := int(start) + n
+	if end > len(f.image) {
+		f.image = append(f.image, make([]byte, end-len(f.image))...)
+	}
+	return f.image[start:end]
+}
+
+// check fails for canceled contexts, fakes that are not connected,
+// addresses outside the model and injected errors. Caller must hold the
+// mutex.
+func (f *FakeClient) check(ctx context.Context, addr vmAddr, injected map[vmAddr]error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if err := f.stateErr(); err != nil {
+		return err
+	}
+	if err := f.model.Validate(addr); err != nil {
+		return err
+	}
+	for failing, err := range injected {
+		if overlaps(addr, failing) {
+			return err
+		}
+	}
+	return nil
+}
+
+// stateErr fails unless the fake is connected, with the error of the last
+// state change when there is one. Caller must hold the mutex.
+func (f *FakeClient) stateErr() error {
+	if f.state == StateConnected {
+		return nil
+	}
+	if f.lastError != nil {
+		return fmt.Errorf("fake client is %s: %w", f.state, f.lastError)
+	}
+	return fmt.Errorf("fake client is %s", f.state)
+}
+
+func overlaps(a, b vmAddr) bool {
+	if a.Type == Bit && b.Type == Bit {
+		return a.Byte == b.Byte && a.Bit == b.Bit
+	}
+	return a.Byte < b.Byte+uint32(b.Type.Size()) && b.Byte < a.Byte+uint32(a.Type.Size())
+}
+
+// read returns the bytes of addr. Caller must hold the mutex.
+func (f *FakeClient) read(ctx context.Context, addr vmAddr, size int) ([]byte, error) {
+	if err := f.check(ctx, addr, f.readErrs); err != nil {
+		return nil, err
+	}
+	if err := f.codec.checkSize(addr, size); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), f.bytes(addr.Byte, size)...), nil
+}
+
+// write stores the encoded buff at addr and records it. Caller must hold
+// the mutex.
+func (f *FakeClient) write(ctx context.Context, addr vmAddr, buff []byte, value uint32) error {
+	if err := f.check(ctx, addr, f.writeErrs); err != nil {
+		return err
+	}
+	if err := f.codec.checkSize(addr, len(buff)); err != nil {
+		return err
+	}
+	f.calls++
+	f.store(addr, buff, value)
+	return nil
+}
+
+// store updates the image and records the write. Caller must hold the mutex.
+func (f *FakeClient) store(addr vmAddr, buff []byte, value uint32) {
+	var data []byte
+	if addr.Type == Bit {
+		_ = f.codec.writeToBuffer(addr, f.bytes(addr.Byte, 1), value)
+		data = []byte{0}
+		if value > 0 {
+			data[0] = 1
+		}
+	} else {
+		copy(f.bytes(addr.Byte, len(buff)), buff)
+		data = append(data, buff...)
+	}
+	f.writes = append(f.writes, FakeWrite{Data: data, VmAddr: addr, Value: value, Call: f.calls})
+}
+
+func (f *FakeClient) Read(addr vmAddr) (uint32, error) {
+	return f.ReadContext(context.Background(), addr)
+}
+
+func (f *FakeClient) ReadContext(ctx context.Context, addr vmAddr) (uint32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	buff, err := f.read(ctx, addr, addr.Type.Size())
+	if err != nil {
+		return 0, err
+	}
+	return f.codec.getIntFromBuffer(addr, buff)
+}
+
+func (f *FakeClient) ReadMany(addrs ...vmAddr) ([]VmAddrResult, error) {
+	return f.ReadManyContext(context.Background(), addrs...)
+}
+
+// ReadManyContext reports injected errors per result, like item errors of
+// a multi read.
+func (f *FakeClient) ReadManyContext(ctx context.Context, addrs ...vmAddr) ([]VmAddrResult, error) {
+	if len(addrs) == 0 {
+		return nil, errors.New("failed `ReadMany`: addrs is empty")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if err := f.stateErr(); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	results := make([]VmAddrResult, len(addrs))
+	for i, addr := range addrs {
+		results[i].VmAddr, results[i].Time = addr, now
+		buff, err := f.read(ctx, addr, addr.Type.Size())
+		if err == nil {
+			results[i].Value, err = f.codec.getIntFromBuffer(addr, buff)
+		}
+		results[i].Err, results[i].Quality = err, qualityOf(err)
+	}
+	return results, nil
+}
+
+func (f *FakeClient) ReadInt16(addr vmAddr) (int16, error) {
+	return f.ReadInt16Context(context.Background(), addr)
+}
+
+func (f *FakeClient) ReadInt16Context(ctx context.Context, addr vmAddr) (int16, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	buff, err := f.read(ctx, addr, 2)
+	if err != nil {
+		return 0, err
+	}
+	var result int16
+	f.codec.helper.GetValueAt(buff, 0, &result)
+	return result, nil
+}
+
+func (f *FakeClient) ReadInt32(addr vmAddr) (int32, error) {
+	return f.ReadInt32Context(context.Background(), addr)
+}
+
+func (f *FakeClient) ReadInt32Context(ctx context.Context, addr vmAddr) (int32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	buff, err := f.read(ctx, addr, 4)
+	if err != nil {
+		return 0, err
+	}
+	var result int32
+	f.codec.helper.GetValueAt(buff, 0, &result)
+	return result, nil
+}
+
+func (f *FakeClient) ReadFloat32(addr vmAddr) (float32, error) {
+	return f.ReadFloat32Context(context.Background(), addr)
+}
+
+func (f *FakeClient) ReadFloat32Context(ctx context.Context, addr vmAddr) (float32, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	buff, err := f.read(ctx, addr, 4)
+	if err != nil {
+		return 0, err
+	}
+	return f.codec.helper.GetRealAt(buff, 0), nil
+}
+
+func (f *FakeClient) Write(addr vmAddr, value uint32) error {
+	return f.WriteContext(context.Background(), addr, value)
+}
+
+func (f *FakeClient) WriteContext(ctx context.Context, addr vmAddr, value uint32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	buff := make([]byte, addr.Type.Size())
+	if err := f.codec.writeToBuffer(addr, buff, value); err != nil {
+		return err
+	}
+	return f.write(ctx, addr, buff, value)
+}
+
+func (f *FakeClient) WriteMany(args ...VmAddrValue) error {
+	return f.WriteManyContext(context.Background(), args...)
+}
+
+// WriteManyContext checks all args first and writes nothing if one fails.
+// Where args overlap, the later one wins.
+func (f *FakeClient) WriteManyContext(ctx context.Context, args ...VmAddrValue) error {
+	if len(args) == 0 {
+		return errors.New("failed `WriteMany`: args is empty")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	bufs := make([][]byte, len(args))
+	for i, arg := range args {
+		if err := f.check(ctx, arg.VmAddr, f.writeErrs); err != nil {
+			var rangeErr *AddressRangeError
+			if errors.As(err, &rangeErr) {
+				return err
+			}
+			return &WriteError{Err: err, Addrs: []vmAddr{arg.VmAddr}}
+		}
+		bufs[i] = make([]byte, arg.VmAddr.Type.Size())
+		if err := f.codec.writeToBuffer(arg.VmAddr, bufs[i], arg.Value); err != nil {
+			return err
+		}
+	}
+	f.calls++
+	for i, arg := range args {
+		f.store(arg.VmAddr, bufs[i], arg.Value)
+	}
+	return nil
+}
+
+func (f *FakeClient) WriteInt16(addr vmAddr, value int16) error {
+	return f.WriteInt16Context(context.Background(), addr, value)
+}
+
+func (f *FakeClient) WriteInt16Context(ctx context.Context, addr vmAddr, value int16) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	buff := make([]byte, 2)
+	f.codec.helper.SetValueAt(buff, 0, value)
+	return f.write(ctx, addr, buff, uint32(uint16(value)))
+}
+
+func (f *FakeClient) WriteInt32(addr vmAddr, value int32) error {
+	return f.WriteInt32Context(context.Background(), addr, value)
+}
+
+func (f *FakeClient) WriteInt32Context(ctx context.Context, addr vmAddr, value int32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	buff := make([]byte, 4)
+	f.codec.helper.SetValueAt(buff, 0, value)
+	return f.write(ctx, addr, buff, uint32(value))
+}
+
+func (f *FakeClient) WriteFloat32(addr vmAddr, value float32) error {
+	return f.WriteFloat32Context(context.Background(), addr, value)
+}
+
+func (f *FakeClient) WriteFloat32Context(ctx context.Context, addr vmAddr, value float32) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	buff := make([]byte, 4)
+	f.codec.helper.SetRealAt(buff, 0, value)
+	return f.write(ctx, addr, buff, math.Float32bits(value))
+}
+
+// Health reports the state set by SetState, connected at first and closed
+// after Disconnect.
+func (f *FakeClient) Health() Health {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return Health{
+		State:         f.state,
+		LastError:     f.lastError,
+		LastErrorTime: f.lastErrorTime,
+		PDULength:     f.pduLength(),
+	}
+}
+
+// pduLength is the PDU length a connected fake negotiated. Caller must hold
+// the mutex.
+func (f *FakeClient) pduLength() int {
+	if f.state == StateConnected {
+		return gos7patch.DefaultPDULength
+	}
+	return 0
+}
+
+// SetState moves the fake to state and notifies the subscribers, to test
+// reactions to connecting, degraded or lost connections. A non-nil err
+// becomes the LastError of Health and the error of the change. Calls fail
+// in all states but StateConnected.
+func (f *FakeClient) SetState(state ConnState, err error) {
+	f.mu.Lock()
+	change := StateChange{Time: time.Now(), Err: err, From: f.state, To: state}
+	f.state = state
+	if err != nil {
+		f.lastError, f.lastErrorTime = err, change.Time
+	}
+	change.PDULength = f.pduLength()
+	subscribers := make([]func(StateChange), 0, len(f.subscribers))
+	for _, fn := range f.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	f.mu.Unlock()
+
+	for _, fn := range subscribers {
+		fn(change)
+	}
+}
+
+func (f *FakeClient) SubscribeState(fn func(StateChange)) (unsubscribe func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.nextSub
+	f.nextSub++
+	f.subscribers[id] = fn
+	return func() {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		delete(f.subscribers, id)
+	}
+}
+
+// Disconnect closes the fake, later calls fail.
+func (f *FakeClient) Disconnect() error {
+	f.mu.Lock()
+	closed := f.state == StateClosed
+	f.mu.Unlock()
+	if !closed {
+		f.SetState(StateClosed, nil)
+	}
+	return nil
+}
@@ -4,6 +4,7 @@ package gos7patch
 // This software may be modified and distributed under the terms
 // of the BSD license. See the LICENSE file for details.
 import (
+	"context"
 	"time"
 )
 
@@ -38,6 +39,13 @@ type Client interface {
 	AGReadMulti(dataItems []S7DataItem, itemsCount int) (err error)
 	//multi write area
 	AGWriteMulti(dataItems []S7DataItem, itemsCount int) (err error)
+	//context aware variants, the context deadline bounds the whole exchange
+	AGReadDBContext(ctx context.Context, dbNumber int, start int, size int, buffer []byte) (err error)
+	AGWriteDBContext(ctx context.Context, dbNumber int, start int, size int, buffer []byte) (err error)
+	//write a single bit of a data block with the S7 bit transport size, the other bits of the byte are left untouched
+	AGWriteDBBitContext(ctx context.Context, dbNumber int, start int, bit int, value bool) (err error)
+	AGReadMultiContext(ctx context.Context, dataItems []S7DataItem, itemsCount int) (err error)
+	AGWriteMultiContext(ctx context.Context, dataItems []S7DataItem, itemsCount int) (err error)
 	/*block*/
 	DBFill(dbnumber int, fillchar int) error
 	DBGet(dbnumber int, usrdata []byte, size int) error
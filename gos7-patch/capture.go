@@ -0,0 +1,242 @@
+package gos7patch
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+const (
+	// pcapng block types
+	pcapngSectionHeader   = 0x0A0D0D0A
+	pcapngInterface       = 0x00000001
+	pcapngEnhancedPacket  = 0x00000006
+	pcapngByteOrderMagic  = 0x1A2B3C4D
+	pcapngLinkTypeRaw     = 101 // raw IPv4 or IPv6, no link layer header
+	pcapngSnapLen         = 0xFFFF
+	captureInitialSeq     = 1
+	tcpFlagFIN            = 0x01
+	tcpFlagSYN            = 0x02
+	tcpFlagPSH            = 0x08
+	tcpFlagACK            = 0x10
+	captureTTL            = 64
+	captureIPv4HeaderSize = 20
+	captureIPv6HeaderSize = 40
+	captureTCPHeaderSize  = 20
+)
+
+// Capture writes the frames a TCPClientHandler exchanges to a pcapng file,
+// so sessions can be analysed with the S7comm dissector of Wireshark where
+// tcpdump is not available:
+//
+//	f, _ := os.Create("session.pcapng")
+//	capture, _ := NewCapture(f)
+//	handler.Capture = capture
+//
+// The TPKT frames are wrapped in synthesized TCP/IP headers using the
+// addresses of the connection, with a handshake when it is established and
+// a FIN when it is closed. Only complete frames are captured: a request is
+// written once it was sent, a response once it was read in full.
+//
+// A Capture can be shared by several handlers. Writing errors do not fail
+// the exchanges, the capture stops instead and Err returns the error.
+type Capture struct {
+	w   io.Writer
+	err error
+	mu  sync.Mutex
+}
+
+// NewCapture writes the pcapng section and interface header to w.
+func NewCapture(w io.Writer) (*Capture, error) {
+	c := &Capture{w: w}
+
+	header := make([]byte, 28+20)
+	// Section Header Block, without options
+	binary.LittleEndian.PutUint32(header[0:], pcapngSectionHeader)
+	binary.LittleEndian.PutUint32(header[4:], 28)
+	binary.LittleEndian.PutUint32(header[8:], pcapngByteOrderMagic)
+	binary.LittleEndian.PutUint16(header[12:], 1) // version 1.0
+	binary.LittleEndian.PutUint64(header[16:], 0xFFFFFFFFFFFFFFFF)
+	binary.LittleEndian.PutUint32(header[24:], 28)
+	// Interface Description Block, microsecond timestamps by default
+	binary.LittleEndian.PutUint32(header[28:], pcapngInterface)
+	binary.LittleEndian.PutUint32(header[32:], 20)
+	binary.LittleEndian.PutUint16(header[36:], pcapngLinkTypeRaw)
+	binary.LittleEndian.PutUint32(header[40:], pcapngSnapLen)
+	binary.LittleEndian.PutUint32(header[44:], 20)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Err returns the first error writing the capture.
+func (c *Capture) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.err
+}
+
+// writePacket writes one IP packet as an Enhanced Packet Block.
+func (c *Capture) writePacket(packet []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.err != nil {
+		return
+	}
+	padded := (len(packet) + 3) &^ 3
+	block := make([]byte, 32+padded)
+	ts := uint64(time.Now().UnixMicro())
+	binary.LittleEndian.PutUint32(block[0:], pcapngEnhancedPacket)
+	binary.LittleEndian.PutUint32(block[4:], uint32(len(block)))
+	binary.LittleEndian.PutUint32(block[8:], 0) // interface
+	binary.LittleEndian.PutUint32(block[12:], uint32(ts>>32))
+	binary.LittleEndian.PutUint32(block[16:], uint32(ts))
+	binary.LittleEndian.PutUint32(block[20:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(block[24:], uint32(len(packet)))
+	copy(block[28:], packet)
+	binary.LittleEndian.PutUint32(block[len(block)-4:], uint32(len(block)))
+	_, c.err = c.w.Write(block)
+}
+
+// open starts the capture of a connection with its handshake.
+func (c *Capture) open(local, remote net.Addr) *captureStream {
+	s := &captureStream{
+		capture:   c,
+		local:     captureEndpoint(local, net.IPv4(10, 0, 0, 1)),
+		remote:    captureEndpoint(remote, net.IPv4(10, 0, 0, 2)),
+		localSeq:  captureInitialSeq,
+		remoteSeq: captureInitialSeq,
+	}
+	if s.local.IP.To4() == nil || s.remote.IP.To4() == nil {
+		s.local.IP, s.remote.IP = s.local.IP.To16(), s.remote.IP.To16()
+	}
+	s.segment(true, tcpFlagSYN, nil)
+	s.segment(false, tcpFlagSYN|tcpFlagACK, nil)
+	s.segment(true, tcpFlagACK, nil)
+	return s
+}
+
+// captureEndpoint returns the TCP address of addr, fallback when it has none.
+func captureEndpoint(addr net.Addr, fallback net.IP) net.TCPAddr {
+	if tcp, ok := addr.(*net.TCPAddr); ok && tcp.IP != nil {
+		return *tcp
+	}
+	return net.TCPAddr{IP: fallback, Port: isoTCP}
+}
+
+// captureStream tracks the sequence numbers of one captured connection.
+// All methods are no-ops on a nil stream.
+type captureStream struct {
+	capture             *Capture
+	local, remote       net.TCPAddr
+	localSeq, remoteSeq uint32
+}
+
+// sent captures a frame sent to the PLC.
+func (s *captureStream) sent(frame []byte) {
+	if s != nil {
+		s.segment(true, tcpFlagPSH|tcpFlagACK, frame)
+	}
+}
+
+// received captures a frame received from the PLC.
+func (s *captureStream) received(frame []byte) {
+	if s != nil {
+		s.segment(false, tcpFlagPSH|tcpFlagACK, frame)
+	}
+}
+
+// close captures the client closing the connection.
+func (s *captureStream) close() {
+	if s != nil {
+		s.segment(true, tcpFlagFIN|tcpFlagACK, nil)
+		s.segment(false, tcpFlagFIN|tcpFlagACK, nil)
+		s.segment(true, tcpFlagACK, nil)
+	}
+}
+
+// segment writes a TCP segment from the client when outgoing, from the PLC
+// otherwise, and advances the sequence number of the sender.
+func (s *captureStream) segment(outgoing bool, flags byte, payload []byte) {
+	src, dst := &s.local, &s.remote
+	seq, ack := &s.localSeq, &s.remoteSeq
+	if !outgoing {
+		src, dst = dst, src
+		seq, ack = ack, seq
+	}
+
+	tcp := make([]byte, captureTCPHeaderSize+len(payload))
+	binary.BigEndian.PutUint16(tcp[0:], uint16(src.Port))
+	binary.BigEndian.PutUint16(tcp[2:], uint16(dst.Port))
+	binary.BigEndian.PutUint32(tcp[4:], *seq)
+	if flags&tcpFlagACK != 0 {
+		binary.BigEndian.PutUint32(tcp[8:], *ack)
+	}
+	tcp[12] = captureTCPHeaderSize / 4 << 4
+	tcp[13] = flags
+	binary.BigEndian.PutUint16(tcp[14:], 0xFFFF) // window
+	copy(tcp[captureTCPHeaderSize:], payload)
+
+	*seq += uint32(len(payload))
+	if flags&(tcpFlagSYN|tcpFlagFIN) != 0 {
+		*seq++
+	}
+
+	var packet []byte
+	if src4, dst4 := src.IP.To4(), dst.IP.To4(); src4 != nil && dst4 != nil {
+		packet = make([]byte, captureIPv4HeaderSize+len(tcp))
+		packet[0] = 0x45
+		binary.BigEndian.PutUint16(packet[2:], uint16(len(packet)))
+		packet[6] = 0x40 // don't fragment
+		packet[8] = captureTTL
+		packet[9] = 6 // TCP
+		copy(packet[12:16], src4)
+		copy(packet[16:20], dst4)
+		binary.BigEndian.PutUint16(packet[10:], checksum(packet[:captureIPv4HeaderSize], 0))
+		binary.BigEndian.PutUint16(tcp[16:], tcpChecksum(src4, dst4, tcp))
+		copy(packet[captureIPv4HeaderSize:], tcp)
+	} else {
+		packet = make([]byte, captureIPv6HeaderSize+len(tcp))
+		packet[0] = 0x60
+		binary.BigEndian.PutUint16(packet[4:], uint16(len(tcp)))
+		packet[6] = 6 // TCP
+		packet[7] = captureTTL
+		copy(packet[8:24], src.IP.To16())
+		copy(packet[24:40], dst.IP.To16())
+		binary.BigEndian.PutUint16(tcp[16:], tcpChecksum(src.IP.To16(), dst.IP.To16(), tcp))
+		copy(packet[captureIPv6HeaderSize:], tcp)
+	}
+	s.capture.writePacket(packet)
+}
+
+// tcpChecksum returns the checksum of a TCP segment including the pseudo
+// header of its IPv4 or IPv6 addresses.
+func tcpChecksum(src, dst net.IP, tcp []byte) uint16 {
+	var sum uint32
+	for _, ip := range [][]byte{src, dst} {
+		for i := 0; i < len(ip); i += 2 {
+			sum += uint32(ip[i])<<8 | uint32(ip[i+1])
+		}
+	}
+	sum += 6 + uint32(len(tcp))
+	return checksum(tcp, sum)
+}
+
+// checksum returns the internet checksum of b added to sum.
+func checksum(b []byte, sum uint32) uint16 {
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum > 0xFFFF {
+		sum = sum>>16 + sum&0xFFFF
+	}
+	return ^uint16(sum)
+}
@@ -4,6 +4,7 @@ package gos7patch
 // This software may be modified and distributed under the terms
 // of the BSD license. See the LICENSE file for details.
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"strconv"
@@ -39,6 +40,7 @@ const (
 	//size header
 	sizeHeaderRead  int = 31 // Header Size when Reading
 	sizeHeaderWrite int = 35 // Header Size when Writing
+	sizeAckHeader   int = 19 // TPKT + COTP + ack data header with error class and code
 
 	// Result transport size
 	tsResBit   = 3
@@ -48,6 +50,13 @@ const (
 	tsResOctet = 9
 )
 
+// Exported area and word length codes for building S7DataItem values.
+const (
+	S7AreaDB = s7areadb
+	S7WLBit  = s7wlbit
+	S7WLByte = s7wlbyte
+)
+
 //PDULength variable to store pdu length after connect
 //var tt, _ := mb.transporter.(*tcpTransporter)tt, _ := mb.transporter.(*tcpTransporter) int //global variable pdulength
 
@@ -73,48 +82,67 @@ func NewClient2(packager Packager, transporter Transporter) Client {
 
 // implement of the interface AGReadDB
 func (mb *client) AGReadDB(dbnumber int, start int, size int, buffer []byte) (err error) {
-	return mb.readArea(s7areadb, dbnumber, start, size, s7wlbyte, buffer)
+	return mb.readArea(context.Background(), s7areadb, dbnumber, start, size, s7wlbyte, buffer)
+}
+
+// implement of the interface AGReadDBContext
+func (mb *client) AGReadDBContext(ctx context.Context, dbnumber int, start int, size int, buffer []byte) (err error) {
+	return mb.readArea(ctx, s7areadb, dbnumber, start, size, s7wlbyte, buffer)
 }
 
 // implement of the interface AGWriteDB
 func (mb *client) AGWriteDB(dbNumber int, start int, size int, buffer []byte) (err error) {
-	return mb.writeArea(s7areadb, dbNumber, start, size, s7wlbyte, buffer)
+	return mb.writeArea(context.Background(), s7areadb, dbNumber, start, size, s7wlbyte, buffer)
+}
+
+// implement of the interface AGWriteDBContext
+func (mb *client) AGWriteDBContext(ctx context.Context, dbNumber int, start int, size int, buffer []byte) (err error) {
+	return mb.writeArea(ctx, s7areadb, dbNumber, start, size, s7wlbyte, buffer)
+}
+
+// implement of the interface AGWriteDBBitContext
+func (mb *client) AGWriteDBBitContext(ctx context.Context, dbNumber int, start int, bit int, value bool) (err error) {
+	buffer := []byte{0}
+	if value {
+		buffer[0] = 1
+	}
+	return mb.writeArea(ctx, s7areadb, dbNumber, start<<3+bit, 1, s7wlbit, buffer)
 }
 
 // implement of the interface AGReadMB
 func (mb *client) AGReadMB(start int, size int, buffer []byte) (err error) {
-	return mb.readArea(s7areamk, 0, start, size, s7wlbyte, buffer)
+	return mb.readArea(context.Background(), s7areamk, 0, start, size, s7wlbyte, buffer)
 }
 
 // implement of the interface AGWriteMB
 func (mb *client) AGWriteMB(start int, size int, buffer []byte) (err error) {
-	return mb.writeArea(s7areamk, 0, start, size, s7wlbyte, buffer)
+	return mb.writeArea(context.Background(), s7areamk, 0, start, size, s7wlbyte, buffer)
 }
 
 // implement of the interface AGReadEB
 func (mb *client) AGReadEB(start int, size int, buffer []byte) (err error) {
-	return mb.readArea(s7areape, 0, start, size, s7wlbyte, buffer)
+	return mb.readArea(context.Background(), s7areape, 0, start, size, s7wlbyte, buffer)
 }
 
 // implement of the interface AGWriteEB
 func (mb *client) AGWriteEB(start int, size int, buffer []byte) (err error) {
-	return mb.writeArea(s7areape, 0, start, size, s7wlbyte, buffer)
+	return mb.writeArea(context.Background(), s7areape, 0, start, size, s7wlbyte, buffer)
 }
 
 // implement of the interface AGReadAB
 func (mb *client) AGReadAB(start int, size int, buffer []byte) (err error) {
-	return mb.readArea(s7areapa, 0, start, size, s7wlbyte, buffer)
+	return mb.readArea(context.Background(), s7areapa, 0, start, size, s7wlbyte, buffer)
 }
 
 // implement of the interface AGWriteAB
 func (mb *client) AGWriteAB(start int, size int, buffer []byte) (err error) {
-	return mb.writeArea(s7areapa, 0, start, size, s7wlbyte, buffer)
+	return mb.writeArea(context.Background(), s7areapa, 0, start, size, s7wlbyte, buffer)
 }
 
 // implement of the interface AGReadTM - read timer
 func (mb *client) AGReadTM(start int, amount int, buffer []byte) (err error) {
 	sbuffer := make([]byte, amount*2)
-	err = mb.readArea(s7areatm, 0, start, amount, s7wltimer, sbuffer)
+	err = mb.readArea(context.Background(), s7areatm, 0, start, amount, s7wltimer, sbuffer)
 	if err == nil {
 		for c := 0; c < amount; c++ {
 			buffer[c] = byte(uint16(sbuffer[c*2+1])<<8 + uint16(sbuffer[c*2]))
@@ -130,14 +158,14 @@ func (mb *client) AGWriteTM(start int, amount int, buffer []byte) (err error) {
 		sbuffer[c*2+1] = byte((uint(buffer[c]) & uint(0xFF00)) >> 8)
 		sbuffer[c*2] = byte(buffer[c] & 0x00FF)
 	}
-	err = mb.writeArea(s7areatm, 0, start, amount, s7wltimer, sbuffer)
+	err = mb.writeArea(context.Background(), s7areatm, 0, start, amount, s7wltimer, sbuffer)
 	return err
 }
 
 // implement of the interface AGReadCT - read counter
 func (mb *client) AGReadCT(start int, amount int, buffer []byte) (err error) {
 	sbuffer := make([]byte, amount*2)
-	err = mb.readArea(s7areact, 0, start, amount, s7wlcounter, sbuffer)
+	err = mb.readArea(context.Background(), s7areact, 0, start, amount, s7wlcounter, sbuffer)
 	if err == nil {
 		for c := 0; c < amount; c++ {
 			buffer[c] = byte(uint(sbuffer[c*2+1])<<8 + uint(sbuffer[c*2]))
@@ -153,12 +181,12 @@ func (mb *client) AGWriteCT(start int, amount int, buffer []byte) (err error) {
 		sbuffer[c*2+1] = byte((uint(buffer[c]) & uint(0xFF00)) >> 8)
 		sbuffer[c*2] = byte(buffer[c] & 0x00FF)
 	}
-	err = mb.writeArea(s7areact, 0, start, amount, s7wlcounter, sbuffer)
+	err = mb.writeArea(context.Background(), s7areact, 0, start, amount, s7wlcounter, sbuffer)
 	return err
 }
 
 // read generic area, pass result into a buffer
-func (mb *client) readArea(area int, dbNumber int, start int, amount int, wordLen int, buffer []byte) (err error) {
+func (mb *client) readArea(ctx context.Context, area int, dbNumber int, start int, amount int, wordLen int, buffer []byte) (err error) {
 	var address, numElements, maxElements, totElements, sizeRequested int
 	offset := 0
 	wordSize := 1
@@ -185,9 +213,7 @@ func (mb *client) readArea(area int, dbNumber int, start int, amount int, wordLe
 		}
 	}
 
-	tt, _ := interface{}(mb.transporter).(*TCPClientHandler)
-
-	maxElements = (tt.PDULength - 18) / wordSize // 18 = Reply telegram header //lth note here
+	maxElements = (PDULength(mb.transporter) - 18) / wordSize // 18 = Reply telegram header //lth note here
 	totElements = amount
 	for totElements > 0 && err == nil {
 		numElements = totElements
@@ -225,15 +251,18 @@ func (mb *client) readArea(area int, dbNumber int, start int, amount int, wordLe
 		address = address >> 8
 		request.Data[28] = byte(address & 0x0FF)
 		var response *ProtocolDataUnit
-		response, sendError := mb.send(&request)
+		response, sendError := mb.sendContext(ctx, &request)
 		err = sendError
 
 		if err == nil {
-			if size := len(response.Data); size < 25 {
+			err = headerError(response)
+		}
+		if err == nil {
+			if size := len(response.Data); size < 25+sizeRequested {
 				err = fmt.Errorf(ErrorText(errIsoInvalidDataSize)+"'%v'", len(response.Data))
 			} else {
 				if response.Data[21] != 0xFF {
-					err = fmt.Errorf(ErrorText(CPUError(uint(response.Data[21]))))
+					err = &ClientError{Code: CPUError(uint(response.Data[21]))}
 				} else {
 					//copy response to buffer
 					copy(buffer[offset:offset+sizeRequested], response.Data[25:25+sizeRequested])
@@ -249,13 +278,14 @@ func (mb *client) readArea(area int, dbNumber int, start int, amount int, wordLe
 }
 
 // writeArea write generic area into PLC with following parameters:
+// 0.ctx: bounds the exchange of every telegram
 // 1.area: s7areape/s7areapa/s7areamk/s7areadb/s7areact/s7areatm
 // 2.dbnumber: specify dbnumber, to use in write DB area, otherwise = 0
 // 3.start: start of the address
 // 4.amount: amount of the address
 // 5.wordlen: bit/byte/word/dword/real/counter/timer
 // 6.buffer: a byte array input for writing
-func (mb *client) writeArea(area int, dbnumber int, start int, amount int, wordlen int, buffer []byte) (err error) {
+func (mb *client) writeArea(ctx context.Context, area int, dbnumber int, start int, amount int, wordlen int, buffer []byte) (err error) {
 	var address, numElements, maxElements, totElements, dataSize, isoSize, length int
 	offset := 0
 	wordSize := 1
@@ -283,8 +313,7 @@ func (mb *client) writeArea(area int, dbnumber int, start int, amount int, wordl
 			wordlen = s7wlbyte
 		}
 	}
-	tt, _ := interface{}(mb.transporter).(*TCPClientHandler)
-	maxElements = (tt.PDULength - 35) / wordSize // 35 = Reply telegram header
+	maxElements = (PDULength(mb.transporter) - 35) / wordSize // 35 = Reply telegram header
 	totElements = amount
 	for totElements > 0 && err == nil {
 		numElements = totElements
@@ -353,12 +382,15 @@ func (mb *client) writeArea(area int, dbnumber int, start int, amount int, wordl
 
 		//expand values into array
 		request.Data = append(request.Data[:35], append(buffer[offset:offset+dataSize], request.Data[35:]...)...)
-		response, sendError := mb.send(&request)
+		response, sendError := mb.sendContext(ctx, &request)
 		err = sendError
+		if err == nil {
+			err = headerError(response)
+		}
 		if err == nil {
 			if length = len(response.Data); length == 22 {
 				if response.Data[21] != byte(0xFF) {
-					err = fmt.Errorf(ErrorText(CPUError(uint(response.Data[21]))))
+					err = &ClientError{Code: CPUError(uint(response.Data[21]))}
 				}
 			} else {
 				err = fmt.Errorf(ErrorText(errIsoInvalidPDU))
@@ -464,9 +496,32 @@ func (mb *client) Read(variable string, buffer []byte) (value interface{}, err e
 	return
 }
 
+// headerError returns the error class and code of an ack data response as
+// a *ClientError, nil when the job succeeded.
+func headerError(response *ProtocolDataUnit) error {
+	if len(response.Data) < sizeAckHeader {
+		return fmt.Errorf(ErrorText(errIsoInvalidPDU))
+	}
+	if code := binary.BigEndian.Uint16(response.Data[17:]); code != 0 {
+		return &ClientError{Code: CPUError(uint(code))}
+	}
+	return nil
+}
+
 // send the package of a pdu request and a pdu response, check for response error and verify the package
 func (mb *client) send(request *ProtocolDataUnit) (response *ProtocolDataUnit, err error) {
-	dataResponse, err := mb.transporter.Send(request.Data)
+	return mb.sendContext(context.Background(), request)
+}
+
+// sendContext is send bounded by ctx. Transporters without context support
+// only get the context checked before the exchange.
+func (mb *client) sendContext(ctx context.Context, request *ProtocolDataUnit) (response *ProtocolDataUnit, err error) {
+	var dataResponse []byte
+	if ct, ok := mb.transporter.(ContextTransporter); ok {
+		dataResponse, err = ct.SendContext(ctx, request.Data)
+	} else if err = ctx.Err(); err == nil {
+		dataResponse, err = mb.transporter.Send(request.Data)
+	}
 	if err != nil {
 		return
 	}
@@ -479,6 +534,11 @@ func (mb *client) send(request *ProtocolDataUnit) (response *ProtocolDataUnit, e
 		err = fmt.Errorf("s7: response data is empty")
 		return
 	}
+	// The TPKT header carries the length of the whole telegram
+	if len(dataResponse) < isoHSize || int(binary.BigEndian.Uint16(dataResponse[2:])) != len(dataResponse) {
+		err = fmt.Errorf("s7: truncated telegram: %s", ErrorText(errIsoInvalidPDU))
+		return
+	}
 	response = &ProtocolDataUnit{
 		Data: dataResponse,
 	}
@@ -73,6 +73,22 @@ const (
 	code7DataOverPDU           = 34048
 )
 
+// ClientError is an error the PLC reported for a request or a single item,
+// Code is one of the client error codes known to ErrorText.
+type ClientError struct {
+	Code int
+}
+
+func (e *ClientError) Error() string {
+	return ErrorText(e.Code)
+}
+
+// AddressError reports whether the PLC rejected the address of the request,
+// i.e. it lies outside the area or the item does not exist.
+func (e *ClientError) AddressError() bool {
+	return e.Code == errCliAddressOutOfRange || e.Code == errCliItemNotAvailable
+}
+
 // ErrorText return a string error text from error code integer
 func ErrorText(err int) string {
 	switch err {
@@ -0,0 +1,267 @@
+package gos7patch
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrFaultInjected is the error of connections dropped by a FaultTransporter.
+var ErrFaultInjected = errors.New("s7: injected fault")
+
+// FaultKind selects what a Fault does to an exchange.
+type FaultKind int
+
+const (
+	// FaultNone passes the exchange through.
+	FaultNone FaultKind = iota
+	// FaultLatency delays the exchange by Delay, or until the context ends.
+	FaultLatency
+	// FaultDisconnect drops the connection before the request is sent. A
+	// transport with a reconnect policy reconnects and sends it anyway.
+	FaultDisconnect
+	// FaultDropResponse lets the PLC process the request, then drops the
+	// connection before the response arrives.
+	FaultDropResponse
+	// FaultTruncate cuts the response after Length bytes, leaving the TPKT
+	// header claiming the full length.
+	FaultTruncate
+	// FaultCorrupt XORs the response byte at Offset with Mask.
+	FaultCorrupt
+	// FaultS7Error answers with ErrorClass and ErrorCode in the ack header
+	// instead of sending the request.
+	FaultS7Error
+)
+
+func (k FaultKind) String() string {
+	switch k {
+	case FaultNone:
+		return "none"
+	case FaultLatency:
+		return "latency"
+	case FaultDisconnect:
+		return "disconnect"
+	case FaultDropResponse:
+		return "drop response"
+	case FaultTruncate:
+		return "truncate"
+	case FaultCorrupt:
+		return "corrupt"
+	case FaultS7Error:
+		return "S7 error"
+	}
+	return "unknown"
+}
+
+// Fault describes what happens to one exchange. Only the fields of its Kind
+// are used.
+type Fault struct {
+	Kind FaultKind
+	// FaultLatency: delay before the exchange
+	Delay time.Duration
+	// FaultTruncate: bytes of the response kept
+	Length int
+	// FaultCorrupt: response byte to change, counted from the end when
+	// negative, and the bits to flip, all when 0
+	Offset int
+	Mask   byte
+	// FaultS7Error: error class and code, e.g. 0x81 0x04 for function not
+	// available or 0x85 0x00 for data exceeding the PDU
+	ErrorClass byte
+	ErrorCode  byte
+	// Probability of the fault per exchange, used by RandomFaults
+	Probability float64
+}
+
+// FaultPlan decides the fault of every exchange.
+type FaultPlan interface {
+	Next(request []byte) Fault
+}
+
+// FaultScript is a FaultPlan applying its faults to consecutive exchanges,
+// one per exchange. Exchanges past the end of the script pass.
+type FaultScript struct {
+	faults []Fault
+	mu     sync.Mutex
+	next   int
+}
+
+// NewFaultScript returns a script of faults, use Fault{} to let an
+// exchange pass.
+func NewFaultScript(faults ...Fault) *FaultScript {
+	return &FaultScript{faults: faults}
+}
+
+func (s *FaultScript) Next(request []byte) Fault {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.next >= len(s.faults) {
+		return Fault{}
+	}
+	s.next++
+	return s.faults[s.next-1]
+}
+
+// RandomFaults is a FaultPlan injecting each of its faults with its
+// Probability, at most one per exchange. The same seed gives the same
+// sequence of faults.
+type RandomFaults struct {
+	rand   *rand.Rand
+	faults []Fault
+	mu     sync.Mutex
+}
+
+func NewRandomFaults(seed int64, faults ...Fault) *RandomFaults {
+	return &RandomFaults{rand: rand.New(rand.NewSource(seed)), faults: faults}
+}
+
+func (r *RandomFaults) Next(request []byte) Fault {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	p := r.rand.Float64()
+	for _, fault := range r.faults {
+		if p < fault.Probability {
+			return fault
+		}
+		p -= fault.Probability
+	}
+	return Fault{}
+}
+
+// FaultTransporter wraps a Transporter and injects the faults of its plan
+// into the exchanges, e.g. to test reconnect and retry handling against a
+// simulator:
+//
+//	handler := NewTCPClientHandler(addr, 0, 1)
+//	faults := NewFaultTransporter(handler, NewFaultScript(Fault{}, Fault{Kind: FaultDisconnect}))
+//	client := NewClient2(handler, faults)
+//
+// Connections are only dropped when the wrapped transporter is a
+// TCPClientHandler; other transporters just fail the exchange.
+type FaultTransporter struct {
+	inner    Transporter
+	plan     FaultPlan
+	injected []Fault
+	mu       sync.Mutex
+}
+
+func NewFaultTransporter(inner Transporter, plan FaultPlan) *FaultTransporter {
+	return &FaultTransporter{inner: inner, plan: plan}
+}
+
+// Injected returns the faults injected so far, FaultNone excluded.
+func (t *FaultTransporter) Injected() []Fault {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]Fault(nil), t.injected...)
+}
+
+// NegotiatedPDULength returns the PDU length of the wrapped transporter.
+func (t *FaultTransporter) NegotiatedPDULength() int {
+	if n, ok := t.inner.(PDUNegotiator); ok {
+		return n.NegotiatedPDULength()
+	}
+	return 0
+}
+
+func (t *FaultTransporter) Send(request []byte) (response []byte, err error) {
+	return t.SendContext(context.Background(), request)
+}
+
+func (t *FaultTransporter) SendContext(ctx context.Context, request []byte) (response []byte, err error) {
+	fault := t.plan.Next(request)
+	if fault.Kind != FaultNone {
+		t.mu.Lock()
+		t.injected = append(t.injected, fault)
+		t.mu.Unlock()
+	}
+
+	switch fault.Kind {
+	case FaultLatency:
+		timer := time.NewTimer(fault.Delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	case FaultDisconnect:
+		if !t.drop() {
+			return nil, ErrFaultInjected
+		}
+	case FaultS7Error:
+		return s7ErrorResponse(request, fault.ErrorClass, fault.ErrorCode), nil
+	}
+
+	response, err = t.send(ctx, request)
+	if err != nil {
+		return
+	}
+	switch fault.Kind {
+	case FaultDropResponse:
+		t.drop()
+		return nil, fmt.Errorf("%w: %w", ErrFaultInjected, io.ErrUnexpectedEOF)
+	case FaultTruncate:
+		if fault.Length < len(response) {
+			response = response[:max(fault.Length, 0)]
+		}
+	case FaultCorrupt:
+		offset := fault.Offset
+		if offset < 0 {
+			offset += len(response)
+		}
+		if offset >= 0 && offset < len(response) {
+			mask := fault.Mask
+			if mask == 0 {
+				mask = 0xFF
+			}
+			response = append([]byte(nil), response...)
+			response[offset] ^= mask
+		}
+	}
+	return
+}
+
+func (t *FaultTransporter) send(ctx context.Context, request []byte) ([]byte, error) {
+	if ct, ok := t.inner.(ContextTransporter); ok {
+		return ct.SendContext(ctx, request)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return t.inner.Send(request)
+}
+
+// drop closes the connection of the wrapped transporter, if it has one.
+func (t *FaultTransporter) drop() bool {
+	d, ok := t.inner.(interface{ dropConnection(err error) })
+	if ok {
+		d.dropConnection(ErrFaultInjected)
+	}
+	return ok
+}
+
+// s7ErrorResponse builds the ack data telegram of a job the PLC refused.
+func s7ErrorResponse(request []byte, class, code byte) []byte {
+	response := []byte{
+		3, 0, 0, byte(sizeAckHeader), // TPKT
+		2, 240, 128, // COTP
+		50, 3, 0, 0, // S7 ack data
+		0, 0, // PDU reference
+		0, 0, // Parameters Length
+		0, 0, // Data Length
+		class, code}
+	if len(request) >= 13 {
+		copy(response[11:13], request[11:13])
+	}
+	binary.BigEndian.PutUint16(response[2:], uint16(len(response)))
+	return response
+}
@@ -4,6 +4,7 @@ package gos7patch
 // This software may be modified and distributed under the terms
 // of the BSD license. See the LICENSE file for details.
 import (
+	"context"
 	"fmt"
 	"strconv"
 )
@@ -36,6 +37,31 @@ type Transporter interface {
 	Send(request []byte) (response []byte, err error)
 }
 
+// ContextTransporter is a Transporter that honours context deadlines and cancellation.
+type ContextTransporter interface {
+	Transporter
+	SendContext(ctx context.Context, request []byte) (response []byte, err error)
+}
+
+// DefaultPDULength is the PDU length assumed for transporters that did not
+// negotiate one.
+const DefaultPDULength = 240
+
+// PDUNegotiator is a Transporter that knows the PDU length it negotiated
+// with the PLC. Requests are split to fit it; transporters without it are
+// assumed to have negotiated DefaultPDULength.
+type PDUNegotiator interface {
+	NegotiatedPDULength() int
+}
+
+// PDULength returns the PDU length requests over t have to fit in.
+func PDULength(t Transporter) int {
+	if n, ok := t.(PDUNegotiator); ok && n.NegotiatedPDULength() > 0 {
+		return n.NegotiatedPDULength()
+	}
+	return DefaultPDULength
+}
+
 // Error converts known s7 exception code to error message.
 func (e *S7Error) Error() string {
 	/* CPU tells there is no peripheral at address */
@@ -4,6 +4,7 @@ package gos7patch
 // This software may be modified and distributed under the terms
 // of the BSD license. See the LICENSE file for details.
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 )
@@ -18,10 +19,17 @@ type S7DataItem struct {
 	Amount   int
 	Data     []byte
 	Error    string
+	// Err is the item error as a *ClientError, Error holds its text
+	Err error
 }
 
 // implement WriteMulti
 func (mb *client) AGWriteMulti(dataItems []S7DataItem, itemsCount int) (err error) {
+	return mb.AGWriteMultiContext(context.Background(), dataItems, itemsCount)
+}
+
+// implement WriteMultiContext
+func (mb *client) AGWriteMultiContext(ctx context.Context, dataItems []S7DataItem, itemsCount int) (err error) {
 	// Checks items
 	if itemsCount > 20 { //max variable is 20
 		err = fmt.Errorf(ErrorText(errCliTooManyItems))
@@ -46,8 +54,11 @@ func (mb *client) AGWriteMulti(dataItems []S7DataItem, itemsCount int) (err erro
 
 		// Adjusts the offset
 		var addr int
-		if dataItems[i].WordLen == s7wlbit || dataItems[i].WordLen == s7wlcounter || dataItems[i].WordLen == s7wltimer {
+		if dataItems[i].WordLen == s7wlcounter || dataItems[i].WordLen == s7wltimer {
 			addr = dataItems[i].Start
+		} else if dataItems[i].WordLen == s7wlbit {
+			addr = dataItems[i].Start << 3
+			addr += dataItems[i].Bit // Add Bit addr
 		} else {
 			addr = dataItems[i].Start * 8
 		}
@@ -92,7 +103,8 @@ func (mb *client) AGWriteMulti(dataItems []S7DataItem, itemsCount int) (err erro
 
 		}
 		copy(s7ItemWrite[4:4+itemDataSize], dataItems[i].Data)
-		if itemDataSize%2 != 0 {
+		// Odd sizes are padded, except for the last item
+		if itemDataSize%2 != 0 && i < itemsCount-1 {
 			s7ItemWrite[itemDataSize+4] = 0
 			itemDataSize++
 		}
@@ -101,24 +113,23 @@ func (mb *client) AGWriteMulti(dataItems []S7DataItem, itemsCount int) (err erro
 		offset = offset + itemDataSize + 4
 		dataLength = dataLength + itemDataSize + 4
 	}
-	tt, _ := interface{}(mb.transporter).(*TCPClientHandler)
 	//Checks the size
-	if offset > tt.PDULength {
+	if offset > PDULength(mb.transporter) {
 		err = fmt.Errorf(ErrorText(errCliSizeOverPDU))
 		return
 	}
 	binary.BigEndian.PutUint16(s7Multi[2:], uint16(offset))      // Whole size
 	binary.BigEndian.PutUint16(s7Multi[15:], uint16(dataLength)) // Whole size
 	request := NewProtocolDataUnit(s7Multi)
-	//debug
-	fmt.Printf("%d", s7Multi)
 	//send
-	response, err := mb.send(&request)
+	response, err := mb.sendContext(ctx, &request)
 	if err == nil {
 		// Check Global Operation Result
-		cpuErr := CPUError(uint(binary.BigEndian.Uint16(response.Data[17:])))
-		if cpuErr != 0 {
-			err = fmt.Errorf(ErrorText(cpuErr))
+		if err = headerError(response); err != nil {
+			return
+		}
+		if len(response.Data) < 21+itemsCount {
+			err = fmt.Errorf(ErrorText(errIsoInvalidPDU))
 			return
 		}
 		if itemsWritten := int(response.Data[20]); itemsWritten != itemsCount || itemsWritten > 20 { //max var = 20
@@ -129,8 +140,10 @@ func (mb *client) AGWriteMulti(dataItems []S7DataItem, itemsCount int) (err erro
 			if response.Data[i+21] == 0xFF {
 
 				dataItems[i].Error = ""
+				dataItems[i].Err = nil
 			} else {
-				dataItems[i].Error = ErrorText(CPUError(uint(response.Data[i+21])))
+				dataItems[i].Err = &ClientError{Code: CPUError(uint(response.Data[i+21]))}
+				dataItems[i].Error = dataItems[i].Err.Error()
 			}
 		}
 	}
@@ -139,6 +152,11 @@ func (mb *client) AGWriteMulti(dataItems []S7DataItem, itemsCount int) (err erro
 
 // implement ReadMulti
 func (mb *client) AGReadMulti(dataItems []S7DataItem, itemsCount int) (err error) {
+	return mb.AGReadMultiContext(context.Background(), dataItems, itemsCount)
+}
+
+// implement ReadMultiContext
+func (mb *client) AGReadMultiContext(ctx context.Context, dataItems []S7DataItem, itemsCount int) (err error) {
 	// Checks items
 	if itemsCount > 20 { //max variable is 20
 		err = fmt.Errorf(ErrorText(errCliTooManyItems))
@@ -182,30 +200,27 @@ func (mb *client) AGReadMulti(dataItems []S7DataItem, itemsCount int) (err error
 		s7Multi = append(s7Multi, s7Item...)
 		offset += len(s7Item)
 	}
-	tt, _ := interface{}(mb.transporter).(*TCPClientHandler)
-	if offset > tt.PDULength {
+	if offset > PDULength(mb.transporter) {
 		err = fmt.Errorf(ErrorText(errCliSizeOverPDU))
 		return
 	}
 	binary.BigEndian.PutUint16(s7Multi[2:], uint16(offset)) // Whole size
 	request := NewProtocolDataUnit(s7Multi)
 	//send
-	response, err := mb.send(&request)
+	response, err := mb.sendContext(ctx, &request)
 	if err != nil {
 		return
 	}
+	// Check Global Operation Result
+	if err = headerError(response); err != nil {
+		return
+	}
 	// Check ISO Length
 	resLength := len(response.Data)
 	if resLength < 22 {
 		err = fmt.Errorf(ErrorText(errIsoInvalidPDU)) // PDU too Small
 		return
 	}
-	// Check Global Operation Result
-	cpuErr := CPUError(uint(binary.BigEndian.Uint16(response.Data[17:])))
-	if cpuErr != 0 {
-		err = fmt.Errorf(ErrorText(cpuErr))
-		return
-	}
 	// Get true ItemsCount
 	itemsRead := int(response.Data[20])
 	s7ItemRead := make([]byte, 1024)
@@ -217,21 +232,31 @@ func (mb *client) AGReadMulti(dataItems []S7DataItem, itemsCount int) (err error
 	offset = 21
 	for i := 0; i < itemsCount; i++ {
 		// Get the Item
-		copy(s7ItemRead[0:resLength-offset], response.Data[offset:resLength])
+		if offset+4 > resLength {
+			err = fmt.Errorf(ErrorText(errIsoInvalidPDU))
+			return
+		}
+		copy(s7ItemRead, response.Data[offset:resLength])
 		if s7ItemRead[0] == 255 {
 			itemSize := int(binary.BigEndian.Uint16(s7ItemRead[2:]))
 			item1 := s7ItemRead[1]
 			if item1 != tsResOctet && item1 != tsResReal && item1 != tsResBit {
 				itemSize = itemSize >> 3
 			}
-			copy(dataItems[i].Data[0:], s7ItemRead[4:4+itemSize])
+			if offset+4+itemSize > resLength {
+				err = fmt.Errorf(ErrorText(errIsoInvalidPDU))
+				return
+			}
+			copy(dataItems[i].Data[0:], response.Data[offset+4:offset+4+itemSize])
 			dataItems[i].Error = ""
+			dataItems[i].Err = nil
 			if itemSize%2 != 0 {
 				itemSize++ // Odd size are rounded
 			}
 			offset = offset + 4 + itemSize
 		} else {
-			dataItems[i].Error = ErrorText(CPUError(uint(s7ItemRead[0])))
+			dataItems[i].Err = &ClientError{Code: CPUError(uint(s7ItemRead[0]))}
+			dataItems[i].Error = dataItems[i].Err.Error()
 			offset += 4 // Skip the Item header
 		}
 	}
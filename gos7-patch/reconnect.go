@@ -0,0 +1,133 @@
+package gos7patch
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrTransportClosed is returned by RestoreContext after Close.
+var ErrTransportClosed = errors.New("s7: transport is closed")
+
+// ReconnectPolicy controls how the transport re-establishes a connection
+// that was closed for being idle or broken. The zero value disables
+// reconnecting.
+type ReconnectPolicy struct {
+	// Number of connection attempts per Send, reconnecting is disabled when zero
+	MaxAttempts int
+	// Delay before the second attempt, the first one is made right away
+	InitialBackoff time.Duration
+	// Upper bound of the delay between two attempts
+	MaxBackoff time.Duration
+	// Factor the delay grows by after each failed attempt, 2 when not set
+	Multiplier float64
+	// Fraction of the delay that is randomized, e.g. 0.2 for +/-20%
+	Jitter float64
+}
+
+// DefaultReconnectPolicy returns a policy of 3 attempts that backs off from
+// 100ms up to 5s with 20% jitter.
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return ReconnectPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+	}
+}
+
+// Backoff returns the delay before the given attempt, counted from 0.
+func (p ReconnectPolicy) Backoff(attempt int) time.Duration {
+	if attempt <= 0 || p.InitialBackoff <= 0 {
+		return 0
+	}
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 2
+	}
+	delay := float64(p.InitialBackoff) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxBackoff > 0 && delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		delay += delay * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
+// SendTrace records the recovery work done on behalf of the Sends made
+// with a context returned by WithSendTrace.
+type SendTrace struct {
+	// Number of successful reconnects
+	Reconnects int
+	// Whether a request was sent again after its connection broke
+	Retried bool
+}
+
+type sendTraceKey struct{}
+
+// WithSendTrace returns a context that makes the transport record into trace.
+func WithSendTrace(ctx context.Context, trace *SendTrace) context.Context {
+	return context.WithValue(ctx, sendTraceKey{}, trace)
+}
+
+func sendTraceFrom(ctx context.Context) *SendTrace {
+	trace, _ := ctx.Value(sendTraceKey{}).(*SendTrace)
+	if trace == nil {
+		return &SendTrace{}
+	}
+	return trace
+}
+
+// reconnect redoes the TCP, ISO and PDU negotiation stages, backing off
+// between attempts. Caller must hold the mutex.
+func (mb *tcpTransporter) reconnect(ctx context.Context, trace *SendTrace) (err error) {
+	for attempt := 0; attempt < mb.Reconnect.MaxAttempts; attempt++ {
+		if delay := mb.Reconnect.Backoff(attempt); delay > 0 {
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				mb.setState(StateDisconnected, ctx.Err())
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		mb.logf("s7: reconnecting to %s, attempt %d", mb.Address, attempt+1)
+		if err = mb.connect(ctx); err == nil {
+			trace.Reconnects++
+			return nil
+		}
+		mb.logf("s7: reconnect failed: %v", err)
+		mb.setState(StateDegraded, err)
+		if ctx.Err() != nil {
+			mb.setState(StateDisconnected, ctx.Err())
+			return ctx.Err()
+		}
+	}
+	mb.setState(StateDisconnected, err)
+	return err
+}
+
+// RestoreContext connects again after the connection was closed for being
+// idle or broke, for callers that recover by themselves while Reconnect is
+// off. It does nothing while connected and fails after Close.
+func (mb *tcpTransporter) RestoreContext(ctx context.Context) error {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if mb.closed {
+		return ErrTransportClosed
+	}
+	if mb.conn != nil {
+		return nil
+	}
+	err := mb.connect(ctx)
+	if err != nil {
+		mb.setState(StateDisconnected, err)
+	}
+	return err
+}
@@ -0,0 +1,240 @@
+package gos7patch
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// ErrNotRecorded is returned by a ReplayTransporter for requests it has no
+// recorded response left for.
+var ErrNotRecorded = errors.New("s7: request not recorded")
+
+// Exchange is one request and its response, as sent by a transporter.
+type Exchange struct {
+	// Time the request was sent
+	Time     time.Time
+	Request  []byte
+	Response []byte
+	// Err is the error text of a failed exchange
+	Err string
+	// Duration until the response arrived
+	Duration time.Duration
+	// PDU length negotiated by the transporter, 0 when unknown
+	PDULength int
+}
+
+// exchangeRecord is the line an Exchange is stored as in a recording.
+type exchangeRecord struct {
+	Time      time.Time `json:"time"`
+	Request   string    `json:"request"`
+	Response  string    `json:"response,omitempty"`
+	Err       string    `json:"error,omitempty"`
+	Duration  int64     `json:"durationUs"`
+	PDULength int       `json:"pduLength,omitempty"`
+}
+
+// RecordingTransporter wraps a Transporter and writes every exchange to w,
+// one JSON object per line with the telegrams in hex:
+//
+//	f, _ := os.Create("session.jsonl")
+//	recorder := NewRecordingTransporter(handler, f)
+//	client := NewClient2(handler, recorder)
+//
+// The recording can be read back with ReadRecording and served by a
+// ReplayTransporter.
+type RecordingTransporter struct {
+	inner Transporter
+	w     io.Writer
+	err   error
+	mu    sync.Mutex
+}
+
+func NewRecordingTransporter(inner Transporter, w io.Writer) *RecordingTransporter {
+	return &RecordingTransporter{inner: inner, w: w}
+}
+
+// Err returns the first error writing the recording. Exchanges are not
+// failed by it, recording stops instead.
+func (t *RecordingTransporter) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.err
+}
+
+// NegotiatedPDULength returns the PDU length of the wrapped transporter.
+func (t *RecordingTransporter) NegotiatedPDULength() int {
+	if n, ok := t.inner.(PDUNegotiator); ok {
+		return n.NegotiatedPDULength()
+	}
+	return 0
+}
+
+func (t *RecordingTransporter) Send(request []byte) (response []byte, err error) {
+	return t.SendContext(context.Background(), request)
+}
+
+func (t *RecordingTransporter) SendContext(ctx context.Context, request []byte) (response []byte, err error) {
+	start := time.Now()
+	if ct, ok := t.inner.(ContextTransporter); ok {
+		response, err = ct.SendContext(ctx, request)
+	} else {
+		response, err = t.inner.Send(request)
+	}
+	exchange := Exchange{
+		Time:      start,
+		Request:   request,
+		Response:  response,
+		Duration:  time.Since(start),
+		PDULength: t.NegotiatedPDULength()}
+	if err != nil {
+		exchange.Err = err.Error()
+	}
+	t.record(exchange)
+	return
+}
+
+func (t *RecordingTransporter) record(exchange Exchange) {
+	line, err := json.Marshal(exchangeRecord{
+		Time:      exchange.Time,
+		Request:   hex.EncodeToString(exchange.Request),
+		Response:  hex.EncodeToString(exchange.Response),
+		Err:       exchange.Err,
+		Duration:  exchange.Duration.Microseconds(),
+		PDULength: exchange.PDULength})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.err != nil {
+		return
+	}
+	if err == nil {
+		// one write per exchange, so a crash leaves complete lines
+		_, err = t.w.Write(append(line, '\n'))
+	}
+	t.err = err
+}
+
+// ReadRecording reads the exchanges written by a RecordingTransporter.
+func ReadRecording(r io.Reader) ([]Exchange, error) {
+	var exchanges []Exchange
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), 1<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var rec exchangeRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return nil, fmt.Errorf("s7: recording line %d: %w", n, err)
+		}
+		request, err := hex.DecodeString(rec.Request)
+		if err != nil {
+			return nil, fmt.Errorf("s7: recording line %d: request: %w", n, err)
+		}
+		response, err := hex.DecodeString(rec.Response)
+		if err != nil {
+			return nil, fmt.Errorf("s7: recording line %d: response: %w", n, err)
+		}
+		exchanges = append(exchanges, Exchange{
+			Time:      rec.Time,
+			Request:   request,
+			Response:  response,
+			Err:       rec.Err,
+			Duration:  time.Duration(rec.Duration) * time.Microsecond,
+			PDULength: rec.PDULength})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("s7: reading recording: %w", err)
+	}
+	return exchanges, nil
+}
+
+// ReplayTransporter answers requests with the responses of a recording
+// instead of talking to a PLC. A request is matched against the recorded
+// requests not served yet, in recording order, ignoring the PDU reference;
+// the same request sent twice gets the responses recorded for it in turn.
+// Failed exchanges are replayed as errors with the recorded text.
+type ReplayTransporter struct {
+	exchanges []Exchange
+	served    []bool
+	mu        sync.Mutex
+}
+
+func NewReplayTransporter(exchanges []Exchange) *ReplayTransporter {
+	return &ReplayTransporter{exchanges: exchanges, served: make([]bool, len(exchanges))}
+}
+
+// Remaining returns the recorded exchanges not replayed yet.
+func (t *ReplayTransporter) Remaining() []Exchange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var remaining []Exchange
+	for i, exchange := range t.exchanges {
+		if !t.served[i] {
+			remaining = append(remaining, exchange)
+		}
+	}
+	return remaining
+}
+
+// NegotiatedPDULength returns the PDU length of the recorded session, so
+// requests are split as they were when recording.
+func (t *ReplayTransporter) NegotiatedPDULength() int {
+	for _, exchange := range t.exchanges {
+		if exchange.PDULength > 0 {
+			return exchange.PDULength
+		}
+	}
+	return 0
+}
+
+func (t *ReplayTransporter) Send(request []byte) (response []byte, err error) {
+	return t.SendContext(context.Background(), request)
+}
+
+func (t *ReplayTransporter) SendContext(ctx context.Context, request []byte) (response []byte, err error) {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i, exchange := range t.exchanges {
+		if t.served[i] || !samePDU(exchange.Request, request) {
+			continue
+		}
+		t.served[i] = true
+		if exchange.Err != "" {
+			return nil, errors.New(exchange.Err)
+		}
+		response = append([]byte(nil), exchange.Response...)
+		if len(response) >= 13 && len(request) >= 13 {
+			copy(response[11:13], request[11:13])
+		}
+		return response, nil
+	}
+	return nil, fmt.Errorf("%w: % x", ErrNotRecorded, request)
+}
+
+// samePDU compares two telegrams ignoring their PDU reference.
+func samePDU(a, b []byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	if len(a) < 13 {
+		return bytes.Equal(a, b)
+	}
+	return bytes.Equal(a[:11], b[:11]) && bytes.Equal(a[13:], b[13:])
+}
@@ -0,0 +1,172 @@
+package gos7patch
+
+import (
+	"sync"
+	"time"
+)
+
+// ConnState is the state of the connection of a transport.
+type ConnState int
+
+const (
+	// StateDisconnected means there is no connection, either not yet
+	// established, closed for being idle or given up after reconnecting failed.
+	StateDisconnected ConnState = iota
+	// StateConnecting means the TCP, ISO and PDU negotiation stages are running.
+	StateConnecting
+	// StateConnected means the connection is negotiated and exchanges succeed.
+	StateConnected
+	// StateDegraded means an established connection broke and has not been
+	// re-established yet.
+	StateDegraded
+	// StateClosed means the connection was closed by Close.
+	StateClosed
+)
+
+func (s ConnState) String() string {
+	switch s {
+	case StateDisconnected:
+		return "disconnected"
+	case StateConnecting:
+		return "connecting"
+	case StateConnected:
+		return "connected"
+	case StateDegraded:
+		return "degraded"
+	case StateClosed:
+		return "closed"
+	}
+	return "unknown"
+}
+
+// StateChange describes a transition of the connection state.
+type StateChange struct {
+	Time time.Time
+	// Error that caused the transition, if any
+	Err       error
+	From      ConnState
+	To        ConnState
+	PDULength int
+}
+
+// Health is a snapshot of the connection state.
+type Health struct {
+	LastErrorTime time.Time
+	// Time of the last exchange that got a response
+	LastExchange time.Time
+	LastError    error
+	State        ConnState
+	PDULength    int
+}
+
+// connState tracks the state of a transport and notifies subscribers. It
+// has its own lock, so Health can be called while a Send is in progress.
+type connState struct {
+	mu            sync.Mutex
+	state         ConnState
+	lastError     error
+	lastErrorTime time.Time
+	lastExchange  time.Time
+	subscribers   map[int]func(StateChange)
+	nextID        int
+	// copy of PDULength that can be read without the transport mutex
+	pduLength int
+}
+
+// Subscribe registers fn to be called on every state change and returns a
+// function that removes it again. fn runs on the goroutine causing the
+// change while the transport is locked: it must not block nor call Send.
+func (mb *tcpTransporter) Subscribe(fn func(StateChange)) (unsubscribe func()) {
+	mb.cs.mu.Lock()
+	defer mb.cs.mu.Unlock()
+
+	if mb.cs.subscribers == nil {
+		mb.cs.subscribers = make(map[int]func(StateChange))
+	}
+	id := mb.cs.nextID
+	mb.cs.nextID++
+	mb.cs.subscribers[id] = fn
+	return func() {
+		mb.cs.mu.Lock()
+		defer mb.cs.mu.Unlock()
+		delete(mb.cs.subscribers, id)
+	}
+}
+
+// State returns the current connection state.
+func (mb *tcpTransporter) State() ConnState {
+	mb.cs.mu.Lock()
+	defer mb.cs.mu.Unlock()
+
+	return mb.cs.state
+}
+
+// Health returns the current connection state, the last error and the time
+// of the last successful exchange.
+func (mb *tcpTransporter) Health() Health {
+	mb.cs.mu.Lock()
+	defer mb.cs.mu.Unlock()
+
+	return Health{
+		State:         mb.cs.state,
+		LastError:     mb.cs.lastError,
+		LastErrorTime: mb.cs.lastErrorTime,
+		LastExchange:  mb.cs.lastExchange,
+		PDULength:     mb.cs.pduLength,
+	}
+}
+
+// setPDULength records a negotiated PDU length. Caller must hold the
+// transport mutex.
+func (mb *tcpTransporter) setPDULength(n int) {
+	mb.PDULength = n
+	mb.cs.mu.Lock()
+	mb.cs.pduLength = n
+	mb.cs.mu.Unlock()
+}
+
+// setState moves to state to and notifies the subscribers. err is recorded
+// as the last error when set. Caller must hold the transport mutex.
+func (mb *tcpTransporter) setState(to ConnState, err error) {
+	mb.cs.mu.Lock()
+	now := time.Now()
+	if err != nil {
+		mb.cs.lastError, mb.cs.lastErrorTime = err, now
+	}
+	from := mb.cs.state
+	if from == to {
+		mb.cs.mu.Unlock()
+		return
+	}
+	mb.cs.state = to
+	pduLength := mb.cs.pduLength
+	subscribers := make([]func(StateChange), 0, len(mb.cs.subscribers))
+	for _, fn := range mb.cs.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	mb.cs.mu.Unlock()
+
+	mb.logf("s7: connection %s -> %s", from, to)
+	change := StateChange{Time: now, Err: err, From: from, To: to, PDULength: pduLength}
+	for _, fn := range subscribers {
+		fn(change)
+	}
+}
+
+// recordExchange records the outcome of an exchange made by Send. Caller
+// must hold the transport mutex.
+func (mb *tcpTransporter) recordExchange(err error) {
+	if err == nil {
+		mb.cs.mu.Lock()
+		mb.cs.lastExchange = time.Now()
+		mb.cs.mu.Unlock()
+		return
+	}
+	if mb.conn == nil && mb.State() == StateConnected {
+		mb.setState(StateDegraded, err)
+		return
+	}
+	mb.cs.mu.Lock()
+	mb.cs.lastError, mb.cs.lastErrorTime = err, time.Now()
+	mb.cs.mu.Unlock()
+}
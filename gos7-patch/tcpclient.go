@@ -4,6 +4,7 @@ package gos7patch
 // This software may be modified and distributed under the terms
 // of the BSD license. See the LICENSE file for details.
 import (
+	"context"
 	"encoding/binary"
 	"fmt"
 	"io"
@@ -100,12 +101,20 @@ type tcpTransporter struct {
 	IdleTimeout time.Duration
 	// Transmission logger
 	Logger *log.Logger
+	// Capture of the exchanged frames as pcapng, none when nil
+	Capture *Capture
+	// Reconnect policy applied when the connection was lost
+	Reconnect ReconnectPolicy
 
 	// TCP connection
 	mu           sync.Mutex
 	conn         net.Conn
+	stream       *captureStream
 	closeTimer   *time.Timer
 	lastActivity time.Time
+	// closed is set by Close and keeps Send from reconnecting
+	closed bool
+	cs     connState
 
 	localTSAP, remoteTSAP uint16
 
@@ -114,7 +123,9 @@ type tcpTransporter struct {
 	ConnectionType                int
 	LastPDUType                   byte
 
-	PDULength int
+	// PDU length requested on connect, pduSizeRequested when not set
+	PDUSizeRequested int
+	PDULength        int
 }
 
 func (mb *tcpTransporter) setConnectionParameters(address string, localTSAP uint16, remoteTSAP uint16) {
@@ -133,8 +144,48 @@ func (mb *tcpTransporter) setConnectionParameters(address string, localTSAP uint
 
 // Send sends data to server and ensures response length is greater than header length.
 func (mb *tcpTransporter) Send(request []byte) (response []byte, err error) {
+	return mb.SendContext(context.Background(), request)
+}
+
+// SendContext is Send bounded by ctx. The context deadline is applied to the
+// socket when it is earlier than Timeout, cancellation interrupts blocked I/O.
+// If the exchange is aborted after the request went out, the connection is
+// closed: the pending response would otherwise be read by the next request.
+//
+// With a Reconnect policy, a connection closed for being idle is
+// re-established before sending, and a request whose connection broke is
+// sent once more over a new one. Both are recorded in the SendTrace of ctx.
+func (mb *tcpTransporter) SendContext(ctx context.Context, request []byte) (response []byte, err error) {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
+
+	trace := sendTraceFrom(ctx)
+	if mb.conn == nil && mb.canReconnect() {
+		if err = mb.reconnect(ctx, trace); err != nil {
+			return
+		}
+	}
+	response, err = mb.exchange(ctx, request)
+	mb.recordExchange(err)
+	// exchange drops the connection when it broke after sending
+	if err != nil && mb.conn == nil && ctx.Err() == nil && mb.canReconnect() {
+		mb.logf("s7: connection lost: %v", err)
+		if rerr := mb.reconnect(ctx, trace); rerr != nil {
+			return
+		}
+		trace.Retried = true
+		response, err = mb.exchange(ctx, request)
+		mb.recordExchange(err)
+	}
+	return
+}
+
+func (mb *tcpTransporter) canReconnect() bool {
+	return mb.Reconnect.MaxAttempts > 0 && !mb.closed
+}
+
+// exchange sends one telegram and reads its response. Caller must hold the mutex.
+func (mb *tcpTransporter) exchange(ctx context.Context, request []byte) (response []byte, err error) {
 	// Set timer to close when idle
 	mb.lastActivity = time.Now()
 	mb.startCloseTimer()
@@ -143,36 +194,63 @@ func (mb *tcpTransporter) Send(request []byte) (response []byte, err error) {
 	if mb.Timeout > 0 {
 		timeout = mb.lastActivity.Add(mb.Timeout)
 	}
+	if deadline, ok := ctx.Deadline(); ok && (timeout.IsZero() || deadline.Before(timeout)) {
+		timeout = deadline
+	}
 	if mb.conn == nil {
 		err = fmt.Errorf("Connection to address %s is null", mb.Address)
 		return
 	}
-	if err = mb.conn.SetDeadline(timeout); err != nil {
+	if err = ctx.Err(); err != nil {
+		return
+	}
+	conn := mb.conn
+	if err = conn.SetDeadline(timeout); err != nil {
 		return
 	}
+	// Unblock pending I/O as soon as the context is cancelled
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Unix(1, 0))
+	})
+	sent := false
+	defer func() {
+		stop()
+		if err == nil {
+			return
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		if sent {
+			mb.logf("s7: closing connection after interrupted exchange: %v", err)
+			_ = mb.close()
+		}
+	}()
 	// Send data
 	mb.logf("s7: sending % x", request)
-	if _, err = mb.conn.Write(request); err != nil {
+	sent = true
+	if _, err = conn.Write(request); err != nil {
 		return
 	}
+	mb.stream.sent(request)
 	done := false
 	data := make([]byte, tcpMaxLength)
 	length := 0
 	for !done && err == nil {
 		// Get TPKT (4 bytes)
-		if _, err = io.ReadFull(mb.conn, data[:4]); err != nil {
+		if _, err = io.ReadFull(conn, data[:4]); err != nil {
 			log.Printf("%T %+v", err, err)
 			return
 		}
 		// Read length, ignore transaction & protocol id (4 bytes)
 		length = int(binary.BigEndian.Uint16(data[2:]))
 		if length == isoHSize {
-			_, err = io.ReadFull(mb.conn, data[4:7])
+			_, err = io.ReadFull(conn, data[4:7])
 			if err != nil { // Skip remaining 3 bytes and Done is still false
 				return
 			}
 		} else {
-			if length > pduSizeRequested+isoHSize || length < minPduSize {
+			if length > mb.pduSizeRequested()+isoHSize || length < minPduSize {
 				err = fmt.Errorf("s7: invalid pdu")
 				return
 			}
@@ -180,17 +258,18 @@ func (mb *tcpTransporter) Send(request []byte) (response []byte, err error) {
 		}
 	}
 	// Skip remaining 3 COTP bytes
-	_, err = io.ReadFull(mb.conn, data[4:7])
+	_, err = io.ReadFull(conn, data[4:7])
 	if err != nil {
 		return
 	}
 	mb.LastPDUType = data[5] // Stores PDU Type, we need it
 	// Receives the S7 Payload
-	_, err = io.ReadFull(mb.conn, data[7:length])
+	_, err = io.ReadFull(conn, data[7:length])
 	if err != nil {
 		return
 	}
 	response = data[0:length]
+	mb.stream.received(response)
 	mb.logf("s7: received % x\n", response)
 	return
 }
@@ -198,17 +277,27 @@ func (mb *tcpTransporter) Send(request []byte) (response []byte, err error) {
 // Connect establishes a new connection to the address in Address.
 // Connect and Close are exported so that multiple requests can be done with one session
 func (mb *tcpTransporter) Connect() error {
-	// mb.mu.Lock()
-	// defer mb.mu.Unlock()
-
-	return mb.connect()
+	return mb.ConnectContext(context.Background())
 }
-func (mb *tcpTransporter) tcpConnect() error {
+
+// ConnectContext is Connect bounded by ctx.
+func (mb *tcpTransporter) ConnectContext(ctx context.Context) error {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
+
+	mb.closed = false
+	err := mb.connect(ctx)
+	if err != nil {
+		mb.setState(StateDisconnected, err)
+	}
+	return err
+}
+
+// tcpConnect dials Address if there is no connection yet. Caller must hold the mutex.
+func (mb *tcpTransporter) tcpConnect(ctx context.Context) error {
 	if mb.conn == nil {
 		dialer := net.Dialer{Timeout: mb.Timeout}
-		conn, err := dialer.Dial("tcp", mb.Address)
+		conn, err := dialer.DialContext(ctx, "tcp", mb.Address)
 		if err != nil {
 			if conn != nil {
 				_ = conn.Close()
@@ -216,29 +305,40 @@ func (mb *tcpTransporter) tcpConnect() error {
 			return err
 		}
 		mb.conn = conn
+		if mb.Capture != nil {
+			mb.stream = mb.Capture.open(conn.LocalAddr(), conn.RemoteAddr())
+		}
 	}
 	return nil
 }
-func (mb *tcpTransporter) connect() error {
+
+// connect runs the three connection stages. Caller must hold the mutex.
+// Failures leave the state Connecting for the caller to resolve.
+func (mb *tcpTransporter) connect(ctx context.Context) error {
+	mb.setState(StateConnecting, nil)
 	//first stage: TCP connection
-	err := mb.tcpConnect()
+	err := mb.tcpConnect(ctx)
 	if err != nil {
 		return err
 	}
 	//second stage: ISOTCP (ISO 8073) Connection
-	err = mb.isoConnect()
+	err = mb.isoConnect(ctx)
 	if err != nil {
-		if mb.conn != nil {
-			_ = mb.conn.Close()
-		}
+		_ = mb.close()
 		return err
 	}
 	// Third stage : S7 protocol data unit negotiation
-	return mb.negotiatePduLength()
-
+	err = mb.negotiatePduLength(ctx)
+	if err != nil {
+		_ = mb.close()
+		return err
+	}
+	mb.recordExchange(nil)
+	mb.setState(StateConnected, nil)
+	return nil
 }
 
-func (mb *tcpTransporter) isoConnect() error {
+func (mb *tcpTransporter) isoConnect(ctx context.Context) error {
 	msg := make([]byte, len(isoConnectionRequestTelegram))
 	copy(msg, isoConnectionRequestTelegram)
 	msg[16] = mb.localTSAPHigh
@@ -247,7 +347,7 @@ func (mb *tcpTransporter) isoConnect() error {
 	msg[21] = mb.remoteTSAPLow
 
 	// Sends the connection request telegram
-	response, err := mb.Send(msg)
+	response, err := mb.exchange(ctx, msg)
 	if size := len(response); size == 22 {
 		if mb.LastPDUType != byte(0xD0) { // 0xD0 = CC Connection confirm
 			err = fmt.Errorf("errIsoConnect")
@@ -257,17 +357,18 @@ func (mb *tcpTransporter) isoConnect() error {
 	}
 	return err
 }
-func (mb *tcpTransporter) negotiatePduLength() error {
+
+func (mb *tcpTransporter) negotiatePduLength(ctx context.Context) error {
 	// Set PDU Size Requested //lth
 	pduSizePackage := make([]byte, len(s7PDUNegogiationTelegram))
 	copy(pduSizePackage, s7PDUNegogiationTelegram)
-	binary.BigEndian.PutUint16(pduSizePackage[23:], uint16(pduSizeRequested))
+	binary.BigEndian.PutUint16(pduSizePackage[23:], uint16(mb.pduSizeRequested()))
 	// Sends the connection request telegram
-	response, err := mb.Send(pduSizePackage)
+	response, err := mb.exchange(ctx, pduSizePackage)
 	length := len(response)
 	if length == 27 && response[17] == 0 && response[18] == 0 { // 20 = size of Negotiate Answer
 		// Get PDU Size Negotiated
-		mb.PDULength = int(binary.BigEndian.Uint16(response[25:]))
+		mb.setPDULength(int(binary.BigEndian.Uint16(response[25:])))
 		if mb.PDULength <= 0 {
 			err = fmt.Errorf(ErrorText(errCliNegotiatingPDU))
 		}
@@ -276,6 +377,22 @@ func (mb *tcpTransporter) negotiatePduLength() error {
 	}
 	return err
 }
+
+// NegotiatedPDULength returns the PDU length negotiated on connect. It is
+// safe to call while another goroutine reconnects.
+func (mb *tcpTransporter) NegotiatedPDULength() int {
+	mb.cs.mu.Lock()
+	defer mb.cs.mu.Unlock()
+
+	return mb.cs.pduLength
+}
+
+func (mb *tcpTransporter) pduSizeRequested() int {
+	if mb.PDUSizeRequested <= 0 {
+		return pduSizeRequested
+	}
+	return mb.PDUSizeRequested
+}
 func (mb *tcpTransporter) startCloseTimer() {
 	if mb.IdleTimeout <= 0 {
 		return
@@ -293,7 +410,23 @@ func (mb *tcpTransporter) Close() error {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
 
-	return mb.close()
+	mb.closed = true
+	err := mb.close()
+	mb.setState(StateClosed, nil)
+	return err
+}
+
+// dropConnection closes the connection the way a broken link would: unlike
+// Close it leaves reconnecting enabled.
+func (mb *tcpTransporter) dropConnection(err error) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	if mb.conn == nil {
+		return
+	}
+	_ = mb.close()
+	mb.recordExchange(err)
 }
 
 // flush flushes pending data in the connection,
@@ -323,6 +456,8 @@ func (mb *tcpTransporter) close() (err error) {
 	if mb.conn != nil {
 		err = mb.conn.Close()
 		mb.conn = nil
+		mb.stream.close()
+		mb.stream = nil
 	}
 	return
 }
@@ -339,6 +474,7 @@ func (mb *tcpTransporter) closeIdle() {
 	if idle >= mb.IdleTimeout {
 		mb.logf("s7: closing connection due to idle timeout: %v", idle)
 		mb.close()
+		mb.setState(StateDisconnected, nil)
 	}
 }
 
@@ -0,0 +1,52 @@
+package gos7logo
+
+import (
+	"context"
+
+	gos7patch "github.com/axon-expert/gos7-logo-client/gos7-patch"
+)
+
+// ConnState is the state of the connection to the LOGO!.
+type ConnState = gos7patch.ConnState
+
+const (
+	StateDisconnected = gos7patch.StateDisconnected
+	StateConnecting   = gos7patch.StateConnecting
+	StateConnected    = gos7patch.StateConnected
+	StateDegraded     = gos7patch.StateDegraded
+	StateClosed       = gos7patch.StateClosed
+)
+
+// StateChange describes a transition of the connection state, including
+// the PDU length negotiated when the connection came up.
+type StateChange = gos7patch.StateChange
+
+// Health is a snapshot of the connection: state, last error, time of the
+// last successful exchange and negotiated PDU length.
+type Health = gos7patch.Health
+
+func (c *client) Health() Health {
+	return c.handler.Health()
+}
+
+// SubscribeState calls fn on every connection state change until the
+// returned function is called. fn must not block nor use the client.
+func (c *client) SubscribeState(fn func(StateChange)) (unsubscribe func()) {
+	return c.handler.Subscribe(fn)
+}
+
+// restorer is implemented by clients that can re-establish a lost
+// connection on request, see Poller.
+type restorer interface {
+	restoreContext(ctx context.Context) error
+}
+
+// restoreContext connects the handler again after an idle close or a broken
+// connection. Clients on a transport given with WithTransport have no
+// connection of their own to restore.
+func (c *client) restoreContext(ctx context.Context) error {
+	if c.external {
+		return nil
+	}
+	return c.handler.RestoreContext(ctx)
+}
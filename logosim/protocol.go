@@ -0,0 +1,316 @@
+package logosim
+
+import (
+	"encoding/binary"
+)
+
+const (
+	tpktVersion   = 3
+	isoHeaderSize = 7 // TPKT + COTP data header
+	minPDULength  = 32
+
+	// COTP PDU types
+	cotpConnectRequest = 0xE0
+	cotpConnectConfirm = 0xD0
+	cotpDisconnect     = 0x80
+	cotpData           = 0xF0
+	// COTP connection request parameters
+	cotpCallingTSAP = 0xC1
+	cotpCalledTSAP  = 0xC2
+
+	s7ProtocolID  = 0x32
+	rosctrJob     = 0x01
+	rosctrAckData = 0x03
+
+	funcSetup = 0xF0
+	funcRead  = 0x04
+	funcWrite = 0x05
+
+	areaDB = 0x84
+
+	// Transport sizes of request items
+	tsBit   = 0x01
+	tsByte  = 0x02
+	tsChar  = 0x03
+	tsWord  = 0x04
+	tsInt   = 0x05
+	tsDWord = 0x06
+	tsDInt  = 0x07
+	tsReal  = 0x08
+	// Transport sizes of data items, lengths of byte and int are in bits
+	tsDataBit  = 0x03
+	tsDataByte = 0x04
+	tsDataInt  = 0x05
+
+	// Item return codes
+	retOK                = 0xFF
+	retAddressOutOfRange = 0x05
+	retInvalidTransport  = 0x06
+	retDataSizeMismatch  = 0x07
+	retNotAvailable      = 0x0A
+
+	// Error class and code of whole jobs
+	errFunctionNotAvailable = 0x8104
+	errDataOverPDU          = 0x8500
+
+	itemSpecSize  = 12
+	ackHeaderSize = 12
+)
+
+// session is the state of one client connection.
+type session struct {
+	// PDU length negotiated, 0 before the setup communication job
+	pduLength int
+	connected bool
+}
+
+// item is a resolved variable of a read or write job.
+type item struct {
+	start int
+	size  int
+	code  byte
+	bit   uint8
+	isBit bool
+}
+
+// handle answers one frame. The connection is closed when keep is false,
+// after reply was sent.
+func (s *Server) handle(sess *session, frame []byte) (reply []byte, keep bool) {
+	switch frame[5] {
+	case cotpConnectRequest:
+		return s.connectConfirm(sess, frame)
+	case cotpData:
+		if !sess.connected {
+			return nil, false
+		}
+		return s.job(sess, frame[isoHeaderSize:])
+	}
+	return nil, false
+}
+
+// connectConfirm accepts an ISO connection request whose TSAPs match the
+// configured ones and refuses it with a disconnect otherwise.
+func (s *Server) connectConfirm(sess *session, frame []byte) ([]byte, bool) {
+	end := 5 + int(frame[4])
+	if len(frame) < end || end < 11 {
+		return nil, false
+	}
+	var local, remote uint16
+	for i := 11; i+2 <= end; {
+		code, n := frame[i], int(frame[i+1])
+		if i+2+n > end {
+			return nil, false
+		}
+		if n == 2 {
+			switch code {
+			case cotpCallingTSAP:
+				local = binary.BigEndian.Uint16(frame[i+2:])
+			case cotpCalledTSAP:
+				remote = binary.BigEndian.Uint16(frame[i+2:])
+			}
+		}
+		i += 2 + n
+	}
+	if (s.cfg.LocalTSAP != 0 && local != s.cfg.LocalTSAP) || (s.cfg.RemoteTSAP != 0 && remote != s.cfg.RemoteTSAP) {
+		s.logf("logosim: refused TSAP %04X/%04X", local, remote)
+		return []byte{tpktVersion, 0, 0, 11, 6, cotpDisconnect, frame[8], frame[9], 0, 1, 0x03}, false
+	}
+	reply := append([]byte(nil), frame[:end]...)
+	binary.BigEndian.PutUint16(reply[2:], uint16(end))
+	reply[5] = cotpConnectConfirm
+	reply[6], reply[7] = frame[8], frame[9]
+	reply[8], reply[9] = 0, 1
+	sess.connected = true
+	return reply, true
+}
+
+// job answers an S7 job. Malformed telegrams close the connection.
+func (s *Server) job(sess *session, pdu []byte) ([]byte, bool) {
+	if len(pdu) < 10 || pdu[0] != s7ProtocolID {
+		return nil, false
+	}
+	ref := pdu[4:6]
+	paramLen := int(binary.BigEndian.Uint16(pdu[6:]))
+	dataLen := int(binary.BigEndian.Uint16(pdu[8:]))
+	if paramLen < 1 || 10+paramLen+dataLen > len(pdu) {
+		return nil, false
+	}
+	if pdu[1] != rosctrJob {
+		return ack(ref, errFunctionNotAvailable, nil, nil), true
+	}
+	params := pdu[10 : 10+paramLen]
+	data := pdu[10+paramLen : 10+paramLen+dataLen]
+	switch params[0] {
+	case funcSetup:
+		return s.setup(sess, ref, params)
+	case funcRead:
+		if sess.pduLength == 0 {
+			break
+		}
+		return s.readVar(sess, ref, params)
+	case funcWrite:
+		if sess.pduLength == 0 {
+			break
+		}
+		return s.writeVar(ref, params, data)
+	}
+	return ack(ref, errFunctionNotAvailable, nil, nil), true
+}
+
+// setup negotiates the PDU length, the smaller of the requested and the
+// configured one.
+func (s *Server) setup(sess *session, ref, params []byte) ([]byte, bool) {
+	if len(params) < 8 {
+		return nil, false
+	}
+	requested := int(binary.BigEndian.Uint16(params[6:]))
+	if requested < minPDULength {
+		return ack(ref, errFunctionNotAvailable, nil, nil), true
+	}
+	sess.pduLength = min(requested, s.cfg.PDULength)
+	reply := append([]byte{funcSetup, 0}, params[2:6]...)
+	reply = binary.BigEndian.AppendUint16(reply, uint16(sess.pduLength))
+	return ack(ref, 0, reply, nil), true
+}
+
+func (s *Server) readVar(sess *session, ref, params []byte) ([]byte, bool) {
+	items, ok := s.items(params)
+	if !ok {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var data []byte
+	for i, it := range items {
+		switch {
+		case it.code != retOK:
+			data = append(data, it.code, 0, 0, 0)
+			continue
+		case it.isBit:
+			data = append(data, retOK, tsDataBit, 0, 1, (s.image[it.start]>>it.bit)&1)
+		default:
+			data = append(data, retOK, tsDataByte)
+			data = binary.BigEndian.AppendUint16(data, uint16(it.size*8))
+			data = append(data, s.image[it.start:it.start+it.size]...)
+		}
+		// odd sizes are padded, except for the last item
+		if it.size%2 != 0 && i < len(items)-1 {
+			data = append(data, 0)
+		}
+	}
+	if ackHeaderSize+2+len(data) > sess.pduLength {
+		return ack(ref, errDataOverPDU, nil, nil), true
+	}
+	return ack(ref, 0, []byte{funcRead, byte(len(items))}, data), true
+}
+
+func (s *Server) writeVar(ref, params, data []byte) ([]byte, bool) {
+	items, ok := s.items(params)
+	if !ok {
+		return nil, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := make([]byte, len(items))
+	offset := 0
+	for i, it := range items {
+		if offset+4 > len(data) {
+			return nil, false
+		}
+		n := int(binary.BigEndian.Uint16(data[offset+2:]))
+		if ts := data[offset+1]; ts == tsDataByte || ts == tsDataInt {
+			n /= 8
+		}
+		if offset+4+n > len(data) {
+			return nil, false
+		}
+		value := data[offset+4 : offset+4+n]
+		offset += 4 + n
+		if n%2 != 0 && i < len(items)-1 {
+			offset++
+		}
+
+		switch {
+		case it.code != retOK:
+			codes[i] = it.code
+		case n != it.size:
+			codes[i] = retDataSizeMismatch
+		case it.isBit:
+			mask := byte(1) << it.bit
+			if value[0]&1 != 0 {
+				s.image[it.start] |= mask
+			} else {
+				s.image[it.start] &^= mask
+			}
+			codes[i] = retOK
+		default:
+			copy(s.image[it.start:], value)
+			codes[i] = retOK
+		}
+	}
+	return ack(ref, 0, []byte{funcWrite, byte(len(items))}, codes), true
+}
+
+// items resolves the variable specifications of a read or write job.
+func (s *Server) items(params []byte) ([]item, bool) {
+	if len(params) < 2 {
+		return nil, false
+	}
+	count := int(params[1])
+	if count == 0 || len(params) < 2+count*itemSpecSize {
+		return nil, false
+	}
+	items := make([]item, count)
+	for i := range items {
+		items[i] = s.resolve(params[2+i*itemSpecSize : 2+(i+1)*itemSpecSize])
+	}
+	return items, true
+}
+
+// resolve maps a variable specification to the VM image.
+func (s *Server) resolve(spec []byte) item {
+	if spec[0] != 0x12 || spec[1] != 0x0A || spec[2] != 0x10 {
+		return item{code: retNotAvailable}
+	}
+	amount := int(binary.BigEndian.Uint16(spec[4:]))
+	db := int(binary.BigEndian.Uint16(spec[6:]))
+	if spec[8] != areaDB || db != s.cfg.DBNumber {
+		return item{code: retNotAvailable}
+	}
+	addr := int(spec[9])<<16 | int(spec[10])<<8 | int(spec[11])
+	it := item{code: retOK, start: addr >> 3}
+	switch spec[3] {
+	case tsBit:
+		if amount != 1 {
+			return item{code: retInvalidTransport}
+		}
+		it.size, it.bit, it.isBit = 1, uint8(addr&7), true
+	case tsByte, tsChar:
+		it.size = amount
+	case tsWord, tsInt:
+		it.size = amount * 2
+	case tsDWord, tsDInt, tsReal:
+		it.size = amount * 4
+	default:
+		return item{code: retInvalidTransport}
+	}
+	if it.start+it.size > len(s.image) {
+		return item{code: retAddressOutOfRange}
+	}
+	return it
+}
+
+// ack builds an ack data telegram.
+func ack(ref []byte, errCode uint16, params, data []byte) []byte {
+	size := isoHeaderSize + ackHeaderSize + len(params) + len(data)
+	b := make([]byte, 0, size)
+	b = append(b, tpktVersion, 0, byte(size>>8), byte(size), 2, cotpData, 0x80)
+	b = append(b, s7ProtocolID, rosctrAckData, 0, 0, ref[0], ref[1])
+	b = binary.BigEndian.AppendUint16(b, uint16(len(params)))
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	b = binary.BigEndian.AppendUint16(b, errCode)
+	b = append(b, params...)
+	return append(b, data...)
+}
@@ -0,0 +1,233 @@
+// Package logosim is an in-process LOGO! simulator for tests. It speaks
+// S7 over ISO-on-TCP (RFC 1006) like a LOGO! 0BA7/0BA8 does and serves
+// reads and writes against an in-memory VM image, so a client can be
+// pointed at it instead of a device:
+//
+//	sim, err := logosim.Listen(logosim.Config{LocalTSAP: 0x0100, RemoteTSAP: 0x0200})
+//	defer sim.Close()
+//	client, err := gos7logo.NewClient(sim.Addr(), 0, 1, 0x0100, 0x0200)
+package logosim
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+)
+
+const (
+	// DefaultSize covers the user VM area and the operand areas of LOGO! 8.
+	DefaultSize = 1470
+	// DefaultPDULength is the PDU length a LOGO! negotiates.
+	DefaultPDULength = 240
+	// Largest TPKT frame accepted from clients.
+	maxFrameSize = 4096
+)
+
+// ErrServerClosed is returned by the server methods after Close.
+var ErrServerClosed = errors.New("logosim: server closed")
+
+// Config configures a Server.
+type Config struct {
+	// Logger of connections and protocol errors, nothing is logged when nil
+	Logger *log.Logger
+	// Address to listen on, a free localhost port when empty
+	Addr string
+	// Number of the DB holding the VM image, 1 when 0
+	DBNumber int
+	// Size of the VM image in bytes, DefaultSize when 0
+	Size int
+	// Largest PDU length negotiated, DefaultPDULength when 0
+	PDULength int
+	// TSAPs a connection request has to carry, any TSAP is accepted when 0.
+	// LocalTSAP is the client side, RemoteTSAP the LOGO! side.
+	LocalTSAP, RemoteTSAP uint16
+}
+
+// Server is a running simulator. Its VM image can be inspected and changed
+// with ReadVM and WriteVM while clients are connected.
+type Server struct {
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+	image    []byte
+	cfg      Config
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	closed   bool
+}
+
+// Listen starts a simulator with cfg.
+func Listen(cfg Config) (*Server, error) {
+	if cfg.Addr == "" {
+		cfg.Addr = "127.0.0.1:0"
+	}
+	if cfg.DBNumber == 0 {
+		cfg.DBNumber = 1
+	}
+	if cfg.Size == 0 {
+		cfg.Size = DefaultSize
+	}
+	if cfg.PDULength == 0 {
+		cfg.PDULength = DefaultPDULength
+	}
+	if cfg.Size < 0 || cfg.Size > 1<<16 {
+		return nil, fmt.Errorf("failed start simulator: invalid image size %d", cfg.Size)
+	}
+	if cfg.PDULength < minPDULength || cfg.PDULength > maxFrameSize-isoHeaderSize {
+		return nil, fmt.Errorf("failed start simulator: invalid PDU length %d", cfg.PDULength)
+	}
+	listener, err := net.Listen("tcp", cfg.Addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed start simulator: %w", err)
+	}
+	s := &Server{
+		listener: listener,
+		conns:    map[net.Conn]struct{}{},
+		image:    make([]byte, cfg.Size),
+		cfg:      cfg,
+	}
+	s.wg.Add(1)
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the address the simulator listens on, e.g. `127.0.0.1:40123`.
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// ReadVM returns a copy of n bytes of the VM image from start on.
+func (s *Server) ReadVM(start, n int) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if start < 0 || n < 0 || start+n > len(s.image) {
+		return nil, fmt.Errorf("failed read VM: V%d..V%d is out of range", start, start+n-1)
+	}
+	return append([]byte(nil), s.image[start:start+n]...), nil
+}
+
+// WriteVM copies data into the VM image at start, e.g. to simulate inputs
+// the program on the LOGO! would set.
+func (s *Server) WriteVM(start int, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if start < 0 || start+len(data) > len(s.image) {
+		return fmt.Errorf("failed write VM: V%d..V%d is out of range", start, start+len(data)-1)
+	}
+	copy(s.image[start:], data)
+	return nil
+}
+
+// DropConnections closes all client connections, as a power cycle or a
+// pulled cable would. The simulator keeps accepting new ones.
+func (s *Server) DropConnections() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+}
+
+// Close stops accepting connections, closes the open ones and waits for
+// their handlers to return.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrServerClosed
+	}
+	s.closed = true
+	err := s.listener.Close()
+	for conn := range s.conns {
+		_ = conn.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return err
+}
+
+func (s *Server) accept() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		if s.closed {
+			s.mu.Unlock()
+			_ = conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.wg.Add(1)
+		s.mu.Unlock()
+		go s.serve(conn)
+	}
+}
+
+// serve answers the telegrams of one connection until it is closed.
+func (s *Server) serve(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+	s.logf("logosim: %s connected", conn.RemoteAddr())
+
+	var sess session
+	for {
+		frame, err := readFrame(conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				s.logf("logosim: %s: %v", conn.RemoteAddr(), err)
+			}
+			return
+		}
+		reply, keep := s.handle(&sess, frame)
+		if reply != nil {
+			if _, err := conn.Write(reply); err != nil {
+				return
+			}
+		}
+		if !keep {
+			s.logf("logosim: %s disconnected", conn.RemoteAddr())
+			return
+		}
+	}
+}
+
+// readFrame reads one TPKT frame.
+func readFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != tpktVersion {
+		return nil, fmt.Errorf("invalid TPKT version %d", header[0])
+	}
+	size := int(header[2])<<8 | int(header[3])
+	if size < isoHeaderSize || size > maxFrameSize {
+		return nil, fmt.Errorf("invalid TPKT length %d", size)
+	}
+	frame := make([]byte, size)
+	copy(frame, header)
+	if _, err := io.ReadFull(r, frame[4:]); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+func (s *Server) logf(format string, v ...any) {
+	if s.cfg.Logger != nil {
+		s.cfg.Logger.Printf(format, v...)
+	}
+}
@@ -0,0 +1,229 @@
+package gos7logo
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// logoSoftColumn maps the header names of a LOGO!Soft Comfort VM mapping
+// export, English and German, to the columns the importer uses.
+func logoSoftColumn(header string) string {
+	switch strings.ToLower(strings.TrimSpace(header)) {
+	case "block", "baustein", "block name", "bausteinname":
+		return "block"
+	case "parameter", "parameter name", "parametername":
+		return "parameter"
+	case "type", "typ", "data type", "datentyp":
+		return "type"
+	case "address", "adresse", "vm address", "vm-adresse", "vm adresse":
+		return "address"
+	case "name", "comment", "kommentar", "description", "beschreibung":
+		return "description"
+	}
+	return ""
+}
+
+// ImportLogoSoft reads a VM parameter mapping table exported from
+// LOGO!Soft Comfort as CSV or tab separated text and returns one tag
+// definition per row. The delimiter and the column order are taken from
+// the header row, which needs at least the block and address columns.
+// Addresses are either VM addresses like `VW12` or plain byte offsets
+// (`12`, `12.3` for bits) qualified by the type column.
+func ImportLogoSoft(r io.Reader) ([]TagDef, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = detectDelimiter(data)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed parse LOGO!Soft export: %w", err)
+	}
+
+	columns := map[string]int{}
+	var defs []TagDef
+	for n, record := range records {
+		if isBlankRecord(record) {
+			continue
+		}
+		if len(columns) == 0 {
+			for i, header := range record {
+				if column := logoSoftColumn(header); column != "" {
+					columns[column] = i
+				}
+			}
+			_, hasBlock := columns["block"]
+			_, hasAddr := columns["address"]
+			if !hasBlock || !hasAddr {
+				return nil, fmt.Errorf("failed parse LOGO!Soft export: line %d is no header with block and address columns", n+1)
+			}
+			continue
+		}
+		field := func(column string) string {
+			i, ok := columns[column]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		def, err := logoSoftTag(field("block"), field("parameter"), field("type"), field("address"), field("description"))
+		if err != nil {
+			return nil, fmt.Errorf("failed parse LOGO!Soft export line %d: %w", n+1, err)
+		}
+		defs = append(defs, def)
+	}
+	if len(columns) == 0 {
+		return nil, fmt.Errorf("failed parse LOGO!Soft export: no header found")
+	}
+	return defs, nil
+}
+
+// detectDelimiter picks the most frequent of tab, semicolon and comma in
+// the first non-empty line.
+func detectDelimiter(data []byte) rune {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		best, count := ',', 0
+		for _, delim := range []rune{'\t', ';', ','} {
+			if n := strings.Count(line, string(delim)); n > count {
+				best, count = delim, n
+			}
+		}
+		return best
+	}
+	return ','
+}
+
+func isBlankRecord(record []string) bool {
+	for _, field := range record {
+		if strings.TrimSpace(field) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// logoSoftTag builds the tag definition of one row.
+func logoSoftTag(block, parameter, typeName, address, description string) (TagDef, error) {
+	if block == "" {
+		return TagDef{}, fmt.Errorf("block is empty")
+	}
+	def := TagDef{Name: logoSoftTagName(block, parameter), Description: description}
+	if def.Description == "" {
+		def.Description = strings.TrimSpace(block + " " + parameter)
+	}
+
+	t, ok := logoSoftType(typeName)
+	if typeName != "" && !ok {
+		return TagDef{}, fmt.Errorf("unknown type `%s`", typeName)
+	}
+	upper := strings.ToUpper(strings.ReplaceAll(address, " ", ""))
+	if strings.HasPrefix(upper, "V") {
+		def.Addr = upper
+	} else {
+		if typeName == "" {
+			return TagDef{}, fmt.Errorf("address `%s` needs a type", address)
+		}
+		byteAddr, bit, hasBit := strings.Cut(upper, ".")
+		if _, err := strconv.ParseUint(byteAddr, 10, 32); err != nil {
+			return TagDef{}, fmt.Errorf("invalid address `%s`", address)
+		}
+		switch t.Size() {
+		case 1:
+			if (t == Bit) != hasBit {
+				return TagDef{}, fmt.Errorf("address `%s` does not fit type `%s`", address, typeName)
+			}
+			def.Addr = "V" + byteAddr
+			if hasBit {
+				def.Addr += "." + bit
+			}
+		case 2:
+			def.Addr = "VW" + byteAddr
+		case 4:
+			def.Addr = "VD" + byteAddr
+		}
+	}
+	if ok {
+		def.Type = logoSoftTypeName(t)
+	}
+	addr, err := def.vmAddr()
+	if err != nil {
+		return TagDef{}, err
+	}
+	// the type column has to agree with a V address, e.g. Word with VW12
+	if ok && ((t == Bit) != (addr.Type == Bit) || t.Size() != addr.Type.Size()) {
+		return TagDef{}, fmt.Errorf("address `%s` does not fit type `%s`", address, typeName)
+	}
+	return def, nil
+}
+
+// logoSoftType maps the type names of LOGO!Soft Comfort to data types.
+func logoSoftType(name string) (DataType, bool) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "bit", "bool", "digital":
+		return Bit, true
+	case "byte":
+		return Byte, true
+	case "word", "analog":
+		return Word, true
+	case "int", "integer", "int16":
+		return Int16, true
+	case "dword", "double word", "doubleword", "doppelwort":
+		return DWord, true
+	case "dint", "int32":
+		return Int32, true
+	case "real", "float", "float32":
+		return Float32, true
+	}
+	return 0, false
+}
+
+// logoSoftTypeName returns the TagDef type of t, empty for the types
+// implied by the VM address.
+func logoSoftTypeName(t DataType) string {
+	switch t {
+	case Int16:
+		return "int16"
+	case Int32:
+		return "int32"
+	case Float32:
+		return "float32"
+	}
+	return ""
+}
+
+// logoSoftTagName joins block and parameter into a tag name, e.g.
+// `B001` and `Current value` into `B001_current_value`.
+func logoSoftTagName(block, parameter string) string {
+	var sb strings.Builder
+	sb.WriteString(strings.ToUpper(strings.TrimSpace(block)))
+	word := false
+	for _, r := range strings.ToLower(parameter) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if !word {
+				sb.WriteByte('_')
+				word = true
+			}
+			sb.WriteRune(r)
+			continue
+		}
+		word = false
+	}
+	return sb.String()
+}
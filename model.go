@@ -0,0 +1,150 @@
+package gos7logo
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Model identifies a LOGO! device generation. The generations mirror block
+// operands to different VM offsets, so operand translation and address
+// validation depend on it.
+type Model int
+
+const (
+	Model0BA8 Model = iota
+	Model0BA7
+	Model8FS4
+
+	// DefaultModel is used when no model is given explicitly.
+	DefaultModel = Model0BA8
+)
+
+// ErrAddressOutOfRange is returned (wrapped in AddressRangeError) for
+// addresses the selected model does not have.
+var ErrAddressOutOfRange = errors.New("address out of range")
+
+// AddressRangeError reports an address rejected before it was sent to the PLC.
+type AddressRangeError struct {
+	Addr  vmAddr
+	Model Model
+}
+
+func (e *AddressRangeError) Error() string {
+	return fmt.Sprintf("V%d (%d bytes) is out of range for LOGO! %s", e.Addr.Byte, e.Addr.Type.Size(), e.Model)
+}
+
+func (e *AddressRangeError) Unwrap() error {
+	return ErrAddressOutOfRange
+}
+
+func (m Model) String() string {
+	switch m {
+	case Model0BA7:
+		return "0BA7"
+	case Model0BA8:
+		return "0BA8"
+	case Model8FS4:
+		return "8.FS4"
+	}
+
+	return fmt.Sprintf("Model(%d)", int(m))
+}
+
+// modelLayout is the VM memory map of a model: the size of its freely
+// parameterizable user area and the fixed areas its operands are mirrored to.
+// Other fixed areas, such as the 0BA8 function keys or the 0BA7 cursor keys
+// and shift register bits, are not mapped and fail validation.
+type modelLayout struct {
+	operands map[Operand]operandArea
+	// user area V0..V(userArea-1)
+	userArea uint32
+}
+
+// layout returns the VM mapping table of the model.
+func (m Model) layout() (modelLayout, bool) {
+	switch m {
+	case Model0BA7:
+		return layout0BA7(), true
+	case Model0BA8:
+		return layout0BA8(), true
+	case Model8FS4:
+		return layout8FS4(), true
+	}
+
+	return modelLayout{}, false
+}
+
+// operandLayout returns the VM area of op according to the VM mapping table
+// of the model. Digital operands are packed 8 per byte starting at bit 0,
+// analog operands occupy one word each.
+func (m Model) operandLayout(op Operand) (operandArea, bool) {
+	layout, ok := m.layout()
+	if !ok {
+		return operandArea{}, false
+	}
+	area, ok := layout.operands[op]
+
+	return area, ok
+}
+
+func layout0BA7() modelLayout {
+	return modelLayout{
+		userArea: 850,
+		operands: map[Operand]operandArea{
+			OperandI:  {start: 923, count: 24, typ: Bit},
+			OperandAI: {start: 926, count: 8, typ: Word},
+			OperandQ:  {start: 942, count: 16, typ: Bit},
+			OperandAQ: {start: 944, count: 2, typ: Word},
+			OperandM:  {start: 948, count: 27, typ: Bit},
+			OperandAM: {start: 952, count: 16, typ: Word},
+		},
+	}
+}
+
+func layout0BA8() modelLayout {
+	return modelLayout{
+		userArea: 850,
+		operands: map[Operand]operandArea{
+			OperandI:   {start: 1024, count: 24, typ: Bit},
+			OperandAI:  {start: 1032, count: 8, typ: Word},
+			OperandC:   {start: 1052, count: 4, typ: Bit},
+			OperandS:   {start: 1056, count: 32, typ: Bit},
+			OperandQ:   {start: 1064, count: 20, typ: Bit},
+			OperandAQ:  {start: 1072, count: 8, typ: Word},
+			OperandM:   {start: 1104, count: 64, typ: Bit},
+			OperandAM:  {start: 1118, count: 64, typ: Word},
+			OperandNI:  {start: 1246, count: 64, typ: Bit},
+			OperandNAI: {start: 1262, count: 32, typ: Word},
+			OperandNQ:  {start: 1390, count: 64, typ: Bit},
+			OperandNAQ: {start: 1406, count: 16, typ: Word},
+		},
+	}
+}
+
+// layout8FS4 is the map of the LOGO! 8.FS4 firmware, which kept the user
+// area and operand offsets of the 0BA8 hardware.
+func layout8FS4() modelLayout {
+	return layout0BA8()
+}
+
+// Validate checks that addr lies completely inside the user VM area or
+// inside one of the operand areas of the model. Fixed areas without an
+// Operand are out of range.
+func (m Model) Validate(addr vmAddr) error {
+	layout, ok := m.layout()
+	size := uint32(addr.Type.Size())
+	if !ok || size == 0 || addr.Bit > 7 {
+		return &AddressRangeError{Addr: addr, Model: m}
+	}
+	end := addr.Byte + size
+	if end <= layout.userArea {
+		return nil
+	}
+	for _, area := range layout.operands {
+		if addr.Byte >= area.start && end <= area.start+area.size() {
+			return nil
+		}
+	}
+
+	return &AddressRangeError{Addr: addr, Model: m}
+}
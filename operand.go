@@ -0,0 +1,99 @@
+package gos7logo
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Operand is a class of LOGO! block operands (inputs, outputs, flags, ...)
+// that the device mirrors into the fixed part of its VM memory.
+type Operand string
+
+const (
+	OperandI   Operand = "I"   // digital inputs
+	OperandAI  Operand = "AI"  // analog inputs
+	OperandQ   Operand = "Q"   // digital outputs
+	OperandAQ  Operand = "AQ"  // analog outputs
+	OperandM   Operand = "M"   // digital flags
+	OperandAM  Operand = "AM"  // analog flags
+	OperandNI  Operand = "NI"  // network digital inputs
+	OperandNAI Operand = "NAI" // network analog inputs
+	OperandNQ  Operand = "NQ"  // network digital outputs
+	OperandNAQ Operand = "NAQ" // network analog outputs
+	OperandC   Operand = "C"   // cursor keys
+	OperandS   Operand = "S"   // shift register bits
+)
+
+// operandArea describes where an operand class lives in VM memory.
+type operandArea struct {
+	start uint32
+	count int
+	typ   DataType
+}
+
+// size returns the number of VM bytes the whole area occupies.
+func (a operandArea) size() uint32 {
+	if a.typ == Bit {
+		return uint32(a.count+7) / 8
+	}
+	return uint32(a.count * a.typ.Size())
+}
+
+// NewOperandVmAddr resolves the n-th operand of class op (1-based, as in
+// LOGO!Soft Comfort) to its VM address using the default model layout.
+func NewOperandVmAddr(op Operand, n int) (vmAddr, error) {
+	return DefaultModel.NewOperandVmAddr(op, n)
+}
+
+// NewOperandVmAddr resolves the n-th operand of class op according to the
+// VM layout of the model.
+func (m Model) NewOperandVmAddr(op Operand, n int) (vmAddr, error) {
+	area, ok := m.operandLayout(op)
+	if !ok {
+		return vmAddr{}, fmt.Errorf("operand `%s` is not supported by LOGO! %s", op, m)
+	}
+	if n < 1 || n > area.count {
+		return vmAddr{}, fmt.Errorf("operand %s%d out of range %s1..%s%d", op, n, op, op, area.count)
+	}
+	idx := uint32(n - 1)
+	if area.typ == Bit {
+		return vmAddr{Type: Bit, Byte: area.start + idx/8, Bit: uint8(idx % 8)}, nil
+	}
+
+	return vmAddr{Type: area.typ, Byte: area.start + idx*uint32(area.typ.Size())}, nil
+}
+
+// NewVmAddrFromOperand parses a LOGO! operand name such as `I3`, `Q2`,
+// `AM12` or `NI40` and resolves it to its VM address using the default
+// model layout. Shift register bits are accepted both as `S5` and in
+// register notation `S1.5`.
+func NewVmAddrFromOperand(operand string) (vmAddr, error) {
+	return DefaultModel.NewVmAddrFromOperand(operand)
+}
+
+// NewVmAddrFromOperand parses a LOGO! operand name and resolves it according
+// to the VM layout of the model.
+func (m Model) NewVmAddrFromOperand(operand string) (vmAddr, error) {
+	name := strings.ToUpper(strings.TrimSpace(operand))
+	match := regexp.MustCompile(`^(NAI|NAQ|AI|AQ|AM|NI|NQ|I|Q|M|C|S)([0-9]{1,3})(?:\.([1-8]))?$`).FindStringSubmatch(name)
+	if match == nil {
+		return vmAddr{}, errors.New("unknown operand format")
+	}
+	op := Operand(match[1])
+	n, err := strconv.Atoi(match[2])
+	if err != nil {
+		return vmAddr{}, fmt.Errorf("`%s` is not digits", match[2])
+	}
+	if match[3] != "" {
+		if op != OperandS {
+			return vmAddr{}, fmt.Errorf("operand `%s` has no bit notation", op)
+		}
+		bit, _ := strconv.Atoi(match[3])
+		n = (n-1)*8 + bit
+	}
+
+	return m.NewOperandVmAddr(op, n)
+}
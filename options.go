@@ -0,0 +1,214 @@
+package gos7logo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	gos7patch "github.com/axon-expert/gos7-logo-client/gos7-patch"
+)
+
+const (
+	// defaultLocalTSAP is the TSAP the client connects from unless set.
+	defaultLocalTSAP = 0x0100
+	// defaultDBNumber is the data block the LOGO! exposes its VM memory as.
+	defaultDBNumber = 1
+	// connectionTypePG connects to the PLC as a programming device.
+	connectionTypePG = 1
+)
+
+// ConnectOpt describes how to connect to a LOGO!. Zero fields fall back to
+// the transport defaults: 10s timeout, 60s idle timeout, PG connection,
+// 480 bytes requested PDU, DB1 and DefaultModel.
+type ConnectOpt struct {
+	// Transmission logger
+	Logger *log.Logger
+	// Capture of the exchanged frames as pcapng for Wireshark
+	Capture *Capture
+	// Reconnect policy, reconnecting is off when nil. A request whose
+	// connection broke is resent, so only enable it if resending writes is safe
+	Reconnect *ReconnectPolicy
+	// Transport sends the requests instead of a connection to Addr, e.g. a
+	// gos7patch.ReplayTransporter; Health stays Disconnected
+	Transport Transporter
+	// WrapTransport wraps the TCP transport the requests are sent with,
+	// e.g. with a gos7patch.FaultTransporter
+	WrapTransport func(Transporter) Transporter
+	// Address of the LOGO!, e.g. `192.168.0.3:102`
+	Addr string
+	// Memory area the VM is read from, only "DB" is supported
+	Area string
+	// Rack and Slot build the remote TSAP when RemoteTSAP is not set
+	Rack int
+	Slot int
+	// ConnectionType is 1 (PG), 2 (OP) or 3 (basic)
+	ConnectionType int
+	// Requested PDU length, the PLC may negotiate a smaller one
+	PDUSize int
+	// Data block the VM is mapped to
+	DBNumber int
+	Model    Model
+	// Connect & Read timeout
+	Timeout time.Duration
+	// Idle timeout to close the connection
+	IdleTimeout time.Duration
+	// TSAPs as configured in LOGO!Soft for the S7 connection
+	LocalTSAP  uint16
+	RemoteTSAP uint16
+}
+
+// Transporter sends S7 telegrams and returns the responses.
+type Transporter = gos7patch.Transporter
+
+// Capture writes the S7 traffic of a client to a pcapng file.
+type Capture = gos7patch.Capture
+
+// NewCapture starts a pcapng capture written to w, see WithCapture.
+func NewCapture(w io.Writer) (*Capture, error) {
+	return gos7patch.NewCapture(w)
+}
+
+// ReconnectPolicy controls the backoff between reconnect attempts after the
+// connection to the LOGO! was closed for being idle or broke.
+type ReconnectPolicy = gos7patch.ReconnectPolicy
+
+// RetryTrace tells whether the operations run with its context had to
+// reconnect or resend a request.
+type RetryTrace = gos7patch.SendTrace
+
+// DefaultReconnectPolicy returns a policy for WithReconnect of 3 attempts
+// backing off from 100ms to 5s.
+func DefaultReconnectPolicy() ReconnectPolicy {
+	return gos7patch.DefaultReconnectPolicy()
+}
+
+// WithRetryTrace returns a context that records reconnects and retries of
+// the client calls made with it into trace.
+func WithRetryTrace(ctx context.Context, trace *RetryTrace) context.Context {
+	return gos7patch.WithSendTrace(ctx, trace)
+}
+
+// Option adjusts a ConnectOpt.
+type Option func(*ConnectOpt)
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *ConnectOpt) { o.Timeout = timeout }
+}
+
+func WithIdleTimeout(timeout time.Duration) Option {
+	return func(o *ConnectOpt) { o.IdleTimeout = timeout }
+}
+
+func WithLogger(logger *log.Logger) Option {
+	return func(o *ConnectOpt) { o.Logger = logger }
+}
+
+func WithConnectionType(connectionType int) Option {
+	return func(o *ConnectOpt) { o.ConnectionType = connectionType }
+}
+
+func WithTSAP(local, remote uint16) Option {
+	return func(o *ConnectOpt) { o.LocalTSAP, o.RemoteTSAP = local, remote }
+}
+
+func WithPDUSize(size int) Option {
+	return func(o *ConnectOpt) { o.PDUSize = size }
+}
+
+func WithReconnect(policy ReconnectPolicy) Option {
+	return func(o *ConnectOpt) { o.Reconnect = &policy }
+}
+
+func WithDBNumber(dbNumber int) Option {
+	return func(o *ConnectOpt) { o.DBNumber = dbNumber }
+}
+
+func WithModel(model Model) Option {
+	return func(o *ConnectOpt) { o.Model = model }
+}
+
+func WithCapture(capture *Capture) Option {
+	return func(o *ConnectOpt) { o.Capture = capture }
+}
+
+func WithTransport(transport Transporter) Option {
+	return func(o *ConnectOpt) { o.Transport = transport }
+}
+
+func WithTransportWrapper(wrap func(Transporter) Transporter) Option {
+	return func(o *ConnectOpt) { o.WrapTransport = wrap }
+}
+
+// NewClientWithOpt connects to the LOGO! described by opt after applying
+// opts to it.
+func NewClientWithOpt(opt ConnectOpt, opts ...Option) (*client, error) {
+	for _, apply := range opts {
+		apply(&opt)
+	}
+	handler, err := opt.handler()
+	if err != nil {
+		return nil, err
+	}
+	var transporter Transporter = handler
+	if opt.Transport != nil {
+		transporter = opt.Transport
+	} else if err := handler.Connect(); err != nil {
+		return nil, err
+	}
+	if opt.WrapTransport != nil {
+		transporter = opt.WrapTransport(transporter)
+	}
+	return &client{
+		area: opt.Area, dbNumber: opt.DBNumber,
+		client:    gos7patch.NewClient2(handler, transporter),
+		handler:   handler,
+		transport: transporter,
+		external:  opt.Transport != nil,
+		model:     opt.Model}, nil
+}
+
+// handler fills in the defaults of opt and builds the transport for it.
+func (opt *ConnectOpt) handler() (*gos7patch.TCPClientHandler, error) {
+	if opt.Addr == "" && opt.Transport == nil {
+		return nil, fmt.Errorf("failed connect: address is empty")
+	}
+	if opt.Area == "" {
+		opt.Area = "DB"
+	}
+	if opt.Area != "DB" {
+		return nil, fmt.Errorf("failed connect: unsupported area `%s`", opt.Area)
+	}
+	if opt.DBNumber == 0 {
+		opt.DBNumber = defaultDBNumber
+	}
+	if opt.DBNumber < 0 {
+		return nil, fmt.Errorf("failed connect: invalid DB number %d", opt.DBNumber)
+	}
+	if opt.ConnectionType == 0 {
+		opt.ConnectionType = connectionTypePG
+	}
+	if opt.LocalTSAP == 0 {
+		opt.LocalTSAP = defaultLocalTSAP
+	}
+	if opt.RemoteTSAP == 0 {
+		opt.RemoteTSAP = uint16(opt.ConnectionType)<<8 + uint16(opt.Rack)*0x20 + uint16(opt.Slot)
+	}
+
+	handler := gos7patch.NewTCPClientHandlerWithTSAP(opt.Addr, opt.Rack, opt.Slot, opt.LocalTSAP, opt.RemoteTSAP)
+	handler.ConnectionType = opt.ConnectionType
+	handler.PDUSizeRequested = opt.PDUSize
+	handler.Logger = opt.Logger
+	handler.Capture = opt.Capture
+	if opt.Reconnect != nil {
+		handler.Reconnect = *opt.Reconnect
+	}
+	if opt.Timeout > 0 {
+		handler.Timeout = opt.Timeout
+	}
+	if opt.IdleTimeout > 0 {
+		handler.IdleTimeout = opt.IdleTimeout
+	}
+	return handler, nil
+}
@@ -0,0 +1,204 @@
+package gos7logo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	gos7patch "github.com/axon-expert/gos7-logo-client/gos7-patch"
+)
+
+const (
+	// Telegram sizes of a multi write job, TPKT and COTP headers included.
+	multiWriteRequestHeader = 19
+	multiWriteRequestItem   = 12
+	multiWriteRequestData   = 4
+	// Largest gap between two addresses that is cheaper to transfer than
+	// the overhead of an extra item in a multi read/write job.
+	coalesceGap = multiReadRequestItem + multiReadResponseItem
+)
+
+// Plan describes how a set of addresses is transferred. Every job is one
+// S7 telegram: a job with a single span is a contiguous range transfer, a
+// job with several spans is a multi item transfer.
+type Plan struct {
+	Jobs []PlanJob
+}
+
+// PlanJob is one telegram of a plan.
+type PlanJob struct {
+	Spans []PlanSpan
+}
+
+// PlanSpan is a contiguous VM byte range (Type Byte) or a single bit
+// (Type Bit) and the addresses it covers, given as indices into the planned
+// addresses in their original order.
+type PlanSpan struct {
+	Addrs []int
+	Type  DataType
+	Size  int
+	Start uint32
+	Bit   uint8
+}
+
+// Contiguous reports whether the job is a single range transfer.
+func (j PlanJob) Contiguous() bool {
+	return len(j.Spans) == 1
+}
+
+func (s PlanSpan) String() string {
+	if s.Type == Bit {
+		return fmt.Sprintf("V%d.%d", s.Start, s.Bit)
+	}
+	return fmt.Sprintf("V%d..V%d", s.Start, s.Start+uint32(s.Size)-1)
+}
+
+func (p Plan) String() string {
+	var sb strings.Builder
+	for n, job := range p.Jobs {
+		if n > 0 {
+			sb.WriteByte('\n')
+		}
+		addrs := 0
+		spans := make([]string, len(job.Spans))
+		for k, span := range job.Spans {
+			spans[k] = span.String()
+			addrs += len(span.Addrs)
+		}
+		kind := "multi"
+		if job.Contiguous() {
+			kind = "range"
+		}
+		fmt.Fprintf(&sb, "job %d: %s %s (%d addrs)", n+1, kind, strings.Join(spans, ", "), addrs)
+	}
+	return sb.String()
+}
+
+// PlanRead returns the plan ReadMany uses for addrs.
+func (c *client) PlanRead(addrs ...vmAddr) Plan {
+	return NewReadPlan(addrs, gos7patch.PDULength(c.transport))
+}
+
+// PlanWrite returns the plan WriteMany uses for args.
+func (c *client) PlanWrite(args ...VmAddrValue) Plan {
+	return NewWritePlan(args, gos7patch.PDULength(c.transport))
+}
+
+// NewReadPlan plans reading addrs over a connection with the given PDU length.
+func NewReadPlan(addrs []vmAddr, pduLength int) Plan {
+	return planReads(addrs, allIndices(len(addrs)), pduLength)
+}
+
+// NewWritePlan plans writing args over a connection with the given PDU length.
+func NewWritePlan(args []VmAddrValue, pduLength int) Plan {
+	addrs := make([]vmAddr, len(args))
+	for i, arg := range args {
+		addrs[i] = arg.VmAddr
+	}
+	return planWrites(addrs, pduLength)
+}
+
+func planReads(addrs []vmAddr, idx []int, pduLength int) Plan {
+	maxSpan := pduLength - multiReadResponseHeader - multiReadResponseItem
+	spans := coalesce(addrs, idx, coalesceGap, maxSpan)
+	return packJobs(spans, func(request, response int, span PlanSpan) (int, int) {
+		return request + multiReadRequestItem, response + multiReadResponseItem + span.Size + span.Size%2
+	}, pduLength)
+}
+
+// planWrites only merges addresses that touch each other, so no byte that
+// is not written by the caller is transferred. Bits become single bit
+// items unless their byte is written as a whole anyway.
+func planWrites(addrs []vmAddr, pduLength int) Plan {
+	maxSpan := pduLength - multiWriteRequestHeader - multiWriteRequestItem - multiWriteRequestData
+	var byteIdx, bitIdx []int
+	for i, addr := range addrs {
+		if addr.Type == Bit {
+			bitIdx = append(bitIdx, i)
+		} else {
+			byteIdx = append(byteIdx, i)
+		}
+	}
+	spans := coalesce(addrs, byteIdx, 0, maxSpan)
+	for _, i := range bitIdx {
+		k := slices.IndexFunc(spans, func(span PlanSpan) bool {
+			return addrs[i].Byte >= span.Start && addrs[i].Byte < span.Start+uint32(span.Size)
+		})
+		if k < 0 {
+			spans = append(spans, PlanSpan{Type: Bit, Start: addrs[i].Byte, Size: 1, Bit: addrs[i].Bit, Addrs: []int{i}})
+			continue
+		}
+		spans[k].Addrs = append(spans[k].Addrs, i)
+		slices.Sort(spans[k].Addrs)
+	}
+	slices.SortStableFunc(spans, func(a, b PlanSpan) int {
+		return int(a.Start) - int(b.Start)
+	})
+	return packJobs(spans, func(request, response int, span PlanSpan) (int, int) {
+		return request + multiWriteRequestItem + multiWriteRequestData + span.Size + span.Size%2, response + 1
+	}, pduLength)
+}
+
+// coalesce merges addresses into spans while the gap to the previous
+// address stays within maxGap and the span within maxSpan bytes.
+func coalesce(addrs []vmAddr, idx []int, maxGap int, maxSpan int) []PlanSpan {
+	sorted := slices.Clone(idx)
+	slices.SortStableFunc(sorted, func(a, b int) int {
+		return compareVmAddrByte(addrs[a], addrs[b])
+	})
+
+	var spans []PlanSpan
+	for _, i := range sorted {
+		start := addrs[i].Byte
+		end := start + uint32(addrs[i].Type.Size())
+		if n := len(spans); n > 0 {
+			last := &spans[n-1]
+			lastEnd := last.Start + uint32(last.Size)
+			if start <= lastEnd+uint32(maxGap) && int(max(end, lastEnd)-last.Start) <= maxSpan {
+				last.Size = int(max(end, lastEnd) - last.Start)
+				last.Addrs = append(last.Addrs, i)
+				continue
+			}
+		}
+		spans = append(spans, PlanSpan{Type: Byte, Start: start, Size: int(end - start), Addrs: []int{i}})
+	}
+	for _, span := range spans {
+		slices.Sort(span.Addrs)
+	}
+
+	return spans
+}
+
+// packJobs groups spans into jobs; grow returns the request and response
+// telegram sizes after adding a span to a job.
+func packJobs(spans []PlanSpan, grow func(request, response int, span PlanSpan) (int, int), pduLength int) Plan {
+	var plan Plan
+	var job PlanJob
+	request, response := multiReadRequestHeader, multiReadResponseHeader
+	for _, span := range spans {
+		nextRequest, nextResponse := grow(request, response, span)
+		if len(job.Spans) == maxMultiItems || nextRequest > pduLength || nextResponse > pduLength {
+			if len(job.Spans) > 0 {
+				plan.Jobs = append(plan.Jobs, job)
+			}
+			job = PlanJob{}
+			request, response = grow(multiReadRequestHeader, multiReadResponseHeader, span)
+		} else {
+			request, response = nextRequest, nextResponse
+		}
+		job.Spans = append(job.Spans, span)
+	}
+	if len(job.Spans) > 0 {
+		plan.Jobs = append(plan.Jobs, job)
+	}
+
+	return plan
+}
+
+func allIndices(n int) []int {
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	return idx
+}
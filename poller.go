@@ -0,0 +1,304 @@
+package gos7logo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ChangeEvent reports a new value of a subscribed address. The first event
+// of a subscription carries the initial value with First set. Read errors
+// are reported once in Err until the address can be read again; New then
+// keeps the last good value with QualityStale. Time is the source timestamp
+// of New.
+type ChangeEvent struct {
+	Time    time.Time
+	Err     error
+	Addr    vmAddr
+	Old     uint32
+	New     uint32
+	Quality Quality
+	First   bool
+}
+
+// Sample returns the new value of the event as a Sample.
+func (ev ChangeEvent) Sample() Sample {
+	return Sample{Time: ev.Time, Value: ev.New, Quality: ev.Quality}
+}
+
+// Poller reads subscribed addresses periodically and reports their changes.
+// Subscriptions of equal interval share one ReadMany per tick.
+//
+// When the connection of the client was closed for being idle or broke,
+// the poller connects again before the next read, backing off like
+// DefaultReconnectPolicy between failed attempts. This does not depend on
+// WithReconnect. Until a read succeeds again the subscriptions get one
+// error event.
+type Poller struct {
+	client Client
+	// time of the next connection attempt and the error of the last one
+	retryAt  time.Time
+	retryErr error
+	groups   map[time.Duration]*pollGroup
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	nextID   int
+	attempts int
+	closed   bool
+}
+
+// Subscription is the registration of one address with a Poller.
+type Subscription struct {
+	fn     func(ChangeEvent)
+	poller *Poller
+	done   chan struct{}
+	// deadband, last, hasValue and failed keep the state of the last read,
+	// they are only used by the polling goroutine
+	deadband *deadbandState
+	lastTime time.Time
+	addr     vmAddr
+	interval time.Duration
+	id       int
+	last     uint32
+	hasValue bool
+	failed   bool
+}
+
+type pollGroup struct {
+	subs     map[int]*Subscription
+	stop     chan struct{}
+	interval time.Duration
+}
+
+func NewPoller(c Client) *Poller {
+	return &Poller{client: c, groups: make(map[time.Duration]*pollGroup)}
+}
+
+// Subscribe polls addr every interval and calls fn with its changes. fn is
+// called from the polling goroutine of the interval, one event at a time.
+func (p *Poller) Subscribe(addr vmAddr, interval time.Duration, fn func(ChangeEvent)) (*Subscription, error) {
+	return p.subscribe(addr, interval, make(chan struct{}), fn, nil)
+}
+
+// SubscribeChan is Subscribe delivering events on a channel with the given
+// buffer size. Polling of the interval waits while the channel is full; the
+// channel is not closed on Unsubscribe.
+func (p *Poller) SubscribeChan(addr vmAddr, interval time.Duration, size int) (*Subscription, <-chan ChangeEvent, error) {
+	ch := make(chan ChangeEvent, size)
+	done := make(chan struct{})
+	sub, err := p.subscribe(addr, interval, done, func(ev ChangeEvent) {
+		select {
+		case ch <- ev:
+		case <-done:
+		}
+	}, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sub, ch, nil
+}
+
+// SubscribeDeadband is Subscribe for analog addresses: a new value is only
+// reported when it passes d, see Deadband.
+func (p *Poller) SubscribeDeadband(addr vmAddr, interval time.Duration, d Deadband, fn func(ChangeEvent)) (*Subscription, error) {
+	if addr.Type == Bit {
+		return nil, fmt.Errorf("failed `Subscribe`: deadband on bit address V%d.%d", addr.Byte, addr.Bit)
+	}
+	return p.subscribe(addr, interval, make(chan struct{}), fn, &deadbandState{config: d})
+}
+
+func (p *Poller) subscribe(addr vmAddr, interval time.Duration, done chan struct{}, fn func(ChangeEvent), deadband *deadbandState) (*Subscription, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("failed `Subscribe`: invalid interval %s", interval)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return nil, fmt.Errorf("failed `Subscribe`: poller is closed")
+	}
+	sub := &Subscription{
+		fn: fn, poller: p, done: done, deadband: deadband,
+		addr: addr, interval: interval, id: p.nextID,
+	}
+	p.nextID++
+	g, ok := p.groups[interval]
+	if !ok {
+		g = &pollGroup{subs: make(map[int]*Subscription), stop: make(chan struct{}), interval: interval}
+		p.groups[interval] = g
+		p.wg.Add(1)
+		go p.run(g)
+	}
+	g.subs[sub.id] = sub
+	return sub, nil
+}
+
+// Addr returns the subscribed address.
+func (s *Subscription) Addr() vmAddr {
+	return s.addr
+}
+
+// Unsubscribe stops reporting changes of the subscription.
+func (s *Subscription) Unsubscribe() {
+	p := s.poller
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	g, ok := p.groups[s.interval]
+	if !ok {
+		return
+	}
+	if _, ok := g.subs[s.id]; !ok {
+		return
+	}
+	delete(g.subs, s.id)
+	close(s.done)
+	if len(g.subs) == 0 {
+		delete(p.groups, s.interval)
+		close(g.stop)
+	}
+}
+
+// Close stops all subscriptions and waits for the polling goroutines.
+func (p *Poller) Close() {
+	p.mu.Lock()
+	p.closed = true
+	for interval, g := range p.groups {
+		for _, sub := range g.subs {
+			close(sub.done)
+		}
+		delete(p.groups, interval)
+		close(g.stop)
+	}
+	p.mu.Unlock()
+
+	p.wg.Wait()
+}
+
+func (p *Poller) run(g *pollGroup) {
+	defer p.wg.Done()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-g.stop
+		cancel()
+	}()
+
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+	for {
+		p.poll(ctx, g)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// poll reads all addresses of the group with one ReadMany and reports the
+// changes to their subscriptions.
+func (p *Poller) poll(ctx context.Context, g *pollGroup) {
+	p.mu.Lock()
+	subs := make([]*Subscription, 0, len(g.subs))
+	for _, sub := range g.subs {
+		subs = append(subs, sub)
+	}
+	p.mu.Unlock()
+	if len(subs) == 0 {
+		return
+	}
+
+	addrs := make([]vmAddr, len(subs))
+	for i, sub := range subs {
+		addrs[i] = sub.addr
+	}
+	var results []VmAddrResult
+	err := p.restore(ctx)
+	if err == nil {
+		results, err = p.client.ReadManyContext(ctx, addrs...)
+	}
+	if ctx.Err() != nil {
+		return
+	}
+	now := time.Now()
+	for i, sub := range subs {
+		result := VmAddrResult{Err: err, Sample: Sample{Time: now, Quality: qualityOf(err)}}
+		if results != nil {
+			result = results[i]
+		}
+		if ev, ok := sub.update(result); ok {
+			select {
+			case <-sub.done:
+			default:
+				sub.fn(ev)
+			}
+		}
+	}
+}
+
+// restore connects the client again when it lost its connection, unless
+// the backoff after the last failed attempt has not passed yet.
+func (p *Poller) restore(ctx context.Context) error {
+	r, ok := p.client.(restorer)
+	if !ok {
+		return nil
+	}
+	if state := p.client.Health().State; state != StateDisconnected && state != StateDegraded {
+		return nil
+	}
+	p.mu.Lock()
+	if time.Now().Before(p.retryAt) {
+		err := p.retryErr
+		p.mu.Unlock()
+		return err
+	}
+	p.mu.Unlock()
+
+	err := r.restoreContext(ctx)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if err != nil {
+		p.attempts++
+		p.retryAt = time.Now().Add(DefaultReconnectPolicy().Backoff(p.attempts))
+		p.retryErr = err
+	} else {
+		p.attempts, p.retryAt, p.retryErr = 0, time.Time{}, nil
+	}
+	return err
+}
+
+// update records the outcome of a read and returns the event to report.
+func (s *Subscription) update(result VmAddrResult) (ChangeEvent, bool) {
+	now := result.Time
+	ev := ChangeEvent{Time: now, Addr: s.addr, Old: s.last}
+	if result.Err != nil {
+		if s.failed {
+			return ev, false
+		}
+		s.failed = true
+		ev.Err, ev.New, ev.Quality = result.Err, s.last, result.Quality
+		if s.hasValue {
+			ev.Time, ev.Quality = s.lastTime, QualityStale
+		}
+		return ev, true
+	}
+	value := result.Value
+	if s.deadband != nil {
+		if s.failed {
+			s.deadband.hasValue = false
+		}
+		if !s.deadband.update(analogValue(s.addr, value), now) {
+			return ev, false
+		}
+	} else if s.hasValue && !s.failed && value == s.last {
+		return ev, false
+	}
+	ev.First = !s.hasValue
+	ev.New, ev.Quality = value, QualityGood
+	s.last, s.lastTime, s.hasValue, s.failed = value, now, true, false
+	return ev, true
+}
@@ -0,0 +1,269 @@
+package gos7logo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	// ErrUnknownTag is returned for names missing from the registry.
+	ErrUnknownTag = errors.New("unknown tag")
+	// ErrReadOnlyTag is returned when writing a tag defined as read-only.
+	ErrReadOnlyTag = errors.New("tag is read-only")
+)
+
+// TagDef is the definition of a named tag as kept in a tag file.
+type TagDef struct {
+	// Scaling of the raw value, raw values are used when nil
+	Scaling *Scaling `json:"scaling,omitempty" yaml:"scaling,omitempty"`
+	Name    string   `json:"name" yaml:"name"`
+	// VM address, e.g. `VW12` or `V10.3`
+	Addr string `json:"addr" yaml:"addr"`
+	// Data type when it differs from the one implied by Addr, e.g. `int16`
+	// for a signed `VW` address or `float32` for a `VD` address
+	Type        string `json:"type,omitempty" yaml:"type,omitempty"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	ReadOnly    bool   `json:"readOnly,omitempty" yaml:"readOnly,omitempty"`
+}
+
+// tagFile is the layout of YAML and JSON tag files.
+type tagFile struct {
+	Tags []TagDef `json:"tags" yaml:"tags"`
+}
+
+// Registry resolves tag names to VM addresses, so values can be read and
+// written by name.
+type Registry struct {
+	tags  map[string]registeredTag
+	names []string
+}
+
+type registeredTag struct {
+	def  TagDef
+	addr vmAddr
+}
+
+// NewRegistry validates defs and builds a registry of them.
+func NewRegistry(defs []TagDef) (*Registry, error) {
+	r := &Registry{tags: make(map[string]registeredTag, len(defs))}
+	for _, def := range defs {
+		if def.Name == "" {
+			return nil, fmt.Errorf("failed register tag at `%s`: name is empty", def.Addr)
+		}
+		if _, ok := r.tags[def.Name]; ok {
+			return nil, fmt.Errorf("failed register tag `%s`: duplicate name", def.Name)
+		}
+		addr, err := def.vmAddr()
+		if err != nil {
+			return nil, fmt.Errorf("failed register tag `%s`: %w", def.Name, err)
+		}
+		if def.Scaling != nil {
+			if err := def.Scaling.validate(); err != nil {
+				return nil, fmt.Errorf("failed register tag `%s`: %w", def.Name, err)
+			}
+		}
+		r.tags[def.Name] = registeredTag{def: def, addr: addr}
+		r.names = append(r.names, def.Name)
+	}
+	return r, nil
+}
+
+// ParseRegistryYAML builds a registry from a YAML tag file.
+func ParseRegistryYAML(data []byte) (*Registry, error) {
+	var file tagFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed parse tag file: %w", err)
+	}
+	return NewRegistry(file.Tags)
+}
+
+// ParseRegistryJSON builds a registry from a JSON tag file.
+func ParseRegistryJSON(data []byte) (*Registry, error) {
+	var file tagFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed parse tag file: %w", err)
+	}
+	return NewRegistry(file.Tags)
+}
+
+// LoadRegistry reads a tag file, `.json` files are parsed as JSON and all
+// others as YAML.
+func LoadRegistry(path string) (*Registry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		return ParseRegistryJSON(data)
+	}
+	return ParseRegistryYAML(data)
+}
+
+// vmAddr parses Addr and applies Type to it.
+func (d TagDef) vmAddr() (vmAddr, error) {
+	addr, err := NewVmAddrFromString(d.Addr)
+	if err != nil {
+		return vmAddr{}, err
+	}
+	if d.Type == "" {
+		return addr, nil
+	}
+	t, err := ParseDataType(d.Type)
+	if err != nil {
+		return vmAddr{}, err
+	}
+	if (t == Bit) != (addr.Type == Bit) || t.Size() != addr.Type.Size() {
+		return vmAddr{}, fmt.Errorf("type `%s` does not fit address `%s`", d.Type, d.Addr)
+	}
+	addr.Type = t
+	return addr, nil
+}
+
+// ParseDataType returns the data type of the given name, e.g. `word`,
+// `int16` or `float32`.
+func ParseDataType(name string) (DataType, error) {
+	switch strings.ToLower(name) {
+	case "bit", "bool":
+		return Bit, nil
+	case "byte":
+		return Byte, nil
+	case "word":
+		return Word, nil
+	case "counter":
+		return Counter, nil
+	case "timer":
+		return Timer, nil
+	case "dword":
+		return DWord, nil
+	case "real":
+		return Real, nil
+	case "int16":
+		return Int16, nil
+	case "int32":
+		return Int32, nil
+	case "float32":
+		return Float32, nil
+	}
+	return 0, fmt.Errorf("unknown data type `%s`", name)
+}
+
+// Names returns the tag names in definition order.
+func (r *Registry) Names() []string {
+	return append([]string(nil), r.names...)
+}
+
+// Def returns the definition of the named tag.
+func (r *Registry) Def(name string) (TagDef, error) {
+	tag, err := r.lookup(name)
+	return tag.def, err
+}
+
+// Addr returns the VM address of the named tag.
+func (r *Registry) Addr(name string) (vmAddr, error) {
+	tag, err := r.lookup(name)
+	return tag.addr, err
+}
+
+func (r *Registry) lookup(name string) (registeredTag, error) {
+	tag, ok := r.tags[name]
+	if !ok {
+		return registeredTag{}, fmt.Errorf("%w `%s`", ErrUnknownTag, name)
+	}
+	return tag, nil
+}
+
+// Read reads the named tag and returns its value in engineering units.
+func (r *Registry) Read(ctx context.Context, c Client, name string) (float64, error) {
+	tag, err := r.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	raw, err := readNumber(ctx, c, tag.addr)
+	if err != nil {
+		return 0, fmt.Errorf("failed read tag `%s`: %w", name, err)
+	}
+	if tag.def.Scaling != nil {
+		return tag.def.Scaling.ToEng(raw), nil
+	}
+	return raw, nil
+}
+
+// Write writes value, given in engineering units, to the named tag.
+func (r *Registry) Write(ctx context.Context, c Client, name string, value float64) error {
+	tag, err := r.lookup(name)
+	if err != nil {
+		return err
+	}
+	if tag.def.ReadOnly {
+		return fmt.Errorf("%w `%s`", ErrReadOnlyTag, name)
+	}
+	if tag.def.Scaling != nil {
+		if value, err = tag.def.Scaling.ToRaw(value); err != nil {
+			return fmt.Errorf("failed write tag `%s`: %w", name, err)
+		}
+	}
+	if err := writeNumber(ctx, c, tag.addr, value); err != nil {
+		return fmt.Errorf("failed write tag `%s`: %w", name, err)
+	}
+	return nil
+}
+
+// readNumber reads addr with the accessor matching its type.
+func readNumber(ctx context.Context, c Client, addr vmAddr) (float64, error) {
+	switch addr.Type {
+	case Int16:
+		v, err := c.ReadInt16Context(ctx, addr)
+		return float64(v), err
+	case Int32:
+		v, err := c.ReadInt32Context(ctx, addr)
+		return float64(v), err
+	case Real, Float32:
+		v, err := c.ReadFloat32Context(ctx, addr)
+		return float64(v), err
+	}
+	v, err := c.ReadContext(ctx, addr)
+	return float64(v), err
+}
+
+// writeNumber rounds value to the type of addr, rejecting values the type
+// cannot hold, and writes it.
+func writeNumber(ctx context.Context, c Client, addr vmAddr, value float64) error {
+	switch addr.Type {
+	case Real, Float32:
+		return c.WriteFloat32Context(ctx, addr, float32(value))
+	}
+	rounded := math.Round(value)
+	var low, high float64
+	switch addr.Type {
+	case Bit:
+		low, high = 0, 1
+	case Byte:
+		low, high = 0, math.MaxUint8
+	case Word, Counter, Timer:
+		low, high = 0, math.MaxUint16
+	case DWord:
+		low, high = 0, math.MaxUint32
+	case Int16:
+		low, high = math.MinInt16, math.MaxInt16
+	case Int32:
+		low, high = math.MinInt32, math.MaxInt32
+	}
+	if math.IsNaN(value) || rounded < low || rounded > high {
+		return fmt.Errorf("value %v out of range of type %v", value, addr.Type)
+	}
+	switch addr.Type {
+	case Int16:
+		return c.WriteInt16Context(ctx, addr, int16(rounded))
+	case Int32:
+		return c.WriteInt32Context(ctx, addr, int32(rounded))
+	}
+	return c.WriteContext(ctx, addr, uint32(rounded))
+}
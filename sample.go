@@ -0,0 +1,73 @@
+package gos7logo
+
+import (
+	"errors"
+	"time"
+
+	gos7patch "github.com/axon-expert/gos7-logo-client/gos7-patch"
+)
+
+// Quality tells how far a sample can be trusted.
+type Quality int
+
+const (
+	// QualityGood is a value read from the LOGO! at Time.
+	QualityGood Quality = iota
+	// QualityStale is the last good value, kept after a later read failed.
+	QualityStale
+	// QualityCommError means the value could not be read because the
+	// connection failed or timed out.
+	QualityCommError
+	// QualityConfigError means the PLC refused the request, e.g. for an
+	// invalid transport size, or the value could not be decoded.
+	QualityConfigError
+	// QualityOutOfRange means the address is outside the VM of the model
+	// or the PLC reported it as not available.
+	QualityOutOfRange
+)
+
+func (q Quality) String() string {
+	switch q {
+	case QualityGood:
+		return "good"
+	case QualityStale:
+		return "stale"
+	case QualityCommError:
+		return "comm error"
+	case QualityConfigError:
+		return "config error"
+	case QualityOutOfRange:
+		return "out of range"
+	}
+	return "unknown"
+}
+
+// Sample is a value together with its source timestamp and quality. Value
+// is only meaningful for QualityGood and QualityStale.
+type Sample struct {
+	Time    time.Time
+	Value   uint32
+	Quality Quality
+}
+
+// qualityOf classifies the error of a read.
+func qualityOf(err error) Quality {
+	if err == nil {
+		return QualityGood
+	}
+	if errors.Is(err, ErrAddressOutOfRange) {
+		return QualityOutOfRange
+	}
+	var clientErr *gos7patch.ClientError
+	if errors.As(err, &clientErr) {
+		if clientErr.AddressError() {
+			return QualityOutOfRange
+		}
+		return QualityConfigError
+	}
+	var s7Err *gos7patch.S7Error
+	if errors.As(err, &s7Err) {
+		return QualityConfigError
+	}
+	return QualityCommError
+}
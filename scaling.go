@@ -0,0 +1,148 @@
+package gos7logo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Scaling converts raw values to engineering units and back, e.g. the
+// 0..1000 counts of a LOGO! analog input to -50..150 °C. It is linear
+// between RawMin/EngMin and RawMax/EngMax, or piecewise linear through
+// Points when they are set.
+//
+// Reads outside the range are extrapolated, or limited to it with Clamp.
+// Writes outside the engineering range are always rejected.
+type Scaling struct {
+	// Engineering unit, e.g. `°C` or `bar`
+	Unit string `json:"unit,omitempty" yaml:"unit,omitempty"`
+	// Breakpoints of a piecewise linear scaling, in increasing raw order
+	Points []ScalePoint `json:"points,omitempty" yaml:"points,omitempty"`
+	RawMin float64      `json:"rawMin,omitempty" yaml:"rawMin,omitempty"`
+	RawMax float64      `json:"rawMax,omitempty" yaml:"rawMax,omitempty"`
+	EngMin float64      `json:"engMin,omitempty" yaml:"engMin,omitempty"`
+	EngMax float64      `json:"engMax,omitempty" yaml:"engMax,omitempty"`
+	Clamp  bool         `json:"clamp,omitempty" yaml:"clamp,omitempty"`
+}
+
+// ScalePoint is a breakpoint of a piecewise linear scaling.
+type ScalePoint struct {
+	Raw float64 `json:"raw" yaml:"raw"`
+	Eng float64 `json:"eng" yaml:"eng"`
+}
+
+// LinearScaling maps rawMin..rawMax to engMin..engMax.
+func LinearScaling(rawMin, rawMax, engMin, engMax float64, unit string) Scaling {
+	return Scaling{RawMin: rawMin, RawMax: rawMax, EngMin: engMin, EngMax: engMax, Unit: unit}
+}
+
+func (s Scaling) points() []ScalePoint {
+	if len(s.Points) > 0 {
+		return s.Points
+	}
+	return []ScalePoint{{Raw: s.RawMin, Eng: s.EngMin}, {Raw: s.RawMax, Eng: s.EngMax}}
+}
+
+func (s Scaling) validate() error {
+	points := s.points()
+	if len(points) < 2 {
+		return errors.New("scaling needs at least two points")
+	}
+	rising := points[1].Eng > points[0].Eng
+	for i := 1; i < len(points); i++ {
+		if points[i].Raw <= points[i-1].Raw {
+			return errors.New("scaling raw values must be strictly increasing")
+		}
+		if points[i].Eng == points[i-1].Eng || (points[i].Eng > points[i-1].Eng) != rising {
+			return errors.New("scaling engineering values must be strictly monotonic")
+		}
+	}
+	return nil
+}
+
+// ToEng converts a raw value to engineering units.
+func (s Scaling) ToEng(raw float64) float64 {
+	points := s.points()
+	first, last := points[0], points[len(points)-1]
+	if s.Clamp {
+		if raw <= first.Raw {
+			return first.Eng
+		}
+		if raw >= last.Raw {
+			return last.Eng
+		}
+	}
+	return interpolate(points, raw, func(p ScalePoint) (float64, float64) { return p.Raw, p.Eng })
+}
+
+// ToRaw converts an engineering value to a raw value and fails for values
+// outside the engineering range.
+func (s Scaling) ToRaw(eng float64) (float64, error) {
+	points := s.points()
+	low, high := points[0].Eng, points[len(points)-1].Eng
+	if low > high {
+		low, high = high, low
+	}
+	if eng < low || eng > high {
+		return 0, fmt.Errorf("value %v%s out of range %v..%v%s", eng, s.Unit, low, high, s.Unit)
+	}
+	if points[0].Eng > points[len(points)-1].Eng {
+		reversed := make([]ScalePoint, len(points))
+		for i, p := range points {
+			reversed[len(points)-1-i] = p
+		}
+		points = reversed
+	}
+	return interpolate(points, eng, func(p ScalePoint) (float64, float64) { return p.Eng, p.Raw }), nil
+}
+
+// interpolate evaluates the polyline through points at x, extrapolating
+// the outer segments. axes returns the x and y of a point.
+func interpolate(points []ScalePoint, x float64, axes func(ScalePoint) (float64, float64)) float64 {
+	i := 1
+	for i < len(points)-1 {
+		if upper, _ := axes(points[i]); x <= upper {
+			break
+		}
+		i++
+	}
+	x0, y0 := axes(points[i-1])
+	x1, y1 := axes(points[i])
+	return y0 + (x-x0)*(y1-y0)/(x1-x0)
+}
+
+// ScaledAddr attaches a scaling to a VM address.
+type ScaledAddr struct {
+	Scaling Scaling
+	Addr    vmAddr
+}
+
+// NewScaledAddr validates scaling and attaches it to addr.
+func NewScaledAddr(addr vmAddr, scaling Scaling) (ScaledAddr, error) {
+	if addr.Type == Bit {
+		return ScaledAddr{}, fmt.Errorf("failed scale bit address V%d.%d", addr.Byte, addr.Bit)
+	}
+	if err := scaling.validate(); err != nil {
+		return ScaledAddr{}, err
+	}
+	return ScaledAddr{Scaling: scaling, Addr: addr}, nil
+}
+
+// Read reads the address and returns its value in engineering units.
+func (s ScaledAddr) Read(ctx context.Context, c Client) (float64, error) {
+	raw, err := readNumber(ctx, c, s.Addr)
+	if err != nil {
+		return 0, err
+	}
+	return s.Scaling.ToEng(raw), nil
+}
+
+// Write converts value from engineering units and writes it, rejecting
+// values outside the engineering range or the range of the address type.
+func (s ScaledAddr) Write(ctx context.Context, c Client, value float64) error {
+	raw, err := s.Scaling.ToRaw(value)
+	if err != nil {
+		return err
+	}
+	return writeNumber(ctx, c, s.Addr, raw)
+}
@@ -0,0 +1,111 @@
+package gos7logo
+
+import (
+	"context"
+	"fmt"
+)
+
+// TagValue lists the Go types a Tag can be bound to.
+type TagValue interface {
+	bool | uint8 | int16 | uint16 | int32 | uint32 | float32
+}
+
+// Tag is a typed handle for a single VM address. The encoding is picked
+// from T, so callers never deal with raw uint32 values.
+type Tag[T TagValue] struct {
+	client Client
+	addr   vmAddr
+}
+
+// NewTag binds T to addr and fails when T does not fit the address type,
+// e.g. a float32 tag on `VW12` or a bool tag on `V3`.
+func NewTag[T TagValue](c Client, addr vmAddr) (*Tag[T], error) {
+	var zero T
+	if !tagFits(zero, addr.Type) {
+		return nil, fmt.Errorf("tag of type %T does not fit address of type %v", zero, addr.Type)
+	}
+	return &Tag[T]{client: c, addr: addr}, nil
+}
+
+func tagFits(value any, t DataType) bool {
+	switch value.(type) {
+	case bool:
+		return t == Bit
+	case uint8:
+		return t == Byte
+	case int16:
+		return t == Word || t == Int16
+	case uint16:
+		return t == Word || t == Int16 || t == Counter || t == Timer
+	case int32, uint32:
+		return t == DWord || t == Int32
+	case float32:
+		return t == Real || t == Float32
+	}
+
+	return false
+}
+
+// Addr returns the address the tag is bound to.
+func (t *Tag[T]) Addr() vmAddr {
+	return t.addr
+}
+
+// Get reads the current value of the tag.
+func (t *Tag[T]) Get(ctx context.Context) (T, error) {
+	var result T
+	var err error
+	switch p := any(&result).(type) {
+	case *bool:
+		var v uint32
+		v, err = t.client.ReadContext(ctx, t.addr)
+		*p = v != 0
+	case *uint8:
+		var v uint32
+		v, err = t.client.ReadContext(ctx, t.addr)
+		*p = uint8(v)
+	case *uint16:
+		var v uint32
+		v, err = t.client.ReadContext(ctx, t.addr)
+		*p = uint16(v)
+	case *uint32:
+		*p, err = t.client.ReadContext(ctx, t.addr)
+	case *int16:
+		*p, err = t.client.ReadInt16Context(ctx, t.addr)
+	case *int32:
+		*p, err = t.client.ReadInt32Context(ctx, t.addr)
+	case *float32:
+		*p, err = t.client.ReadFloat32Context(ctx, t.addr)
+	}
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// Set writes value to the tag.
+func (t *Tag[T]) Set(ctx context.Context, value T) error {
+	switch v := any(value).(type) {
+	case bool:
+		var bit uint32
+		if v {
+			bit = 1
+		}
+		return t.client.WriteContext(ctx, t.addr, bit)
+	case uint8:
+		return t.client.WriteContext(ctx, t.addr, uint32(v))
+	case uint16:
+		return t.client.WriteContext(ctx, t.addr, uint32(v))
+	case uint32:
+		return t.client.WriteContext(ctx, t.addr, v)
+	case int16:
+		return t.client.WriteInt16Context(ctx, t.addr, v)
+	case int32:
+		return t.client.WriteInt32Context(ctx, t.addr, v)
+	case float32:
+		return t.client.WriteFloat32Context(ctx, t.addr, v)
+	}
+
+	return fmt.Errorf("unsupported tag type %T", value)
+}
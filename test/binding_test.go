@@ -0,0 +1,118 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+// memClient keeps batch writes per address and serves them to batch reads.
+type memClient struct {
+	gos7logo.Client
+	values map[gos7logo.VmAddr]uint32
+	calls  int
+}
+
+func (c *memClient) ReadManyContext(ctx context.Context, addrs ...gos7logo.VmAddr) ([]gos7logo.VmAddrResult, error) {
+	c.calls++
+	results := make([]gos7logo.VmAddrResult, len(addrs))
+	for i, addr := range addrs {
+		results[i] = gos7logo.VmAddrResult{VmAddr: addr, Sample: gos7logo.Sample{Value: c.values[addr]}}
+	}
+	return results, nil
+}
+
+func (c *memClient) WriteManyContext(ctx context.Context, args ...gos7logo.VmAddrValue) error {
+	c.calls++
+	for _, arg := range args {
+		c.values[arg.VmAddr] = arg.Value
+	}
+	return nil
+}
+
+type pump struct {
+	Name    string
+	Running bool    `logo:"V10.0"`
+	Speed   int16   `logo:"VW12"`
+	Hours   uint32  `logo:"VD20"`
+	Flow    float32 `logo:"VD24"`
+	Level   uint16  `logo:"AI1"`
+}
+
+func TestStructBinding(t *testing.T) {
+	c := &memClient{values: map[gos7logo.VmAddr]uint32{}}
+	ctx := context.Background()
+	in := pump{Running: true, Speed: -120, Hours: 70000, Flow: 2.5, Level: 512}
+	if err := gos7logo.WriteStruct(ctx, c, &in); err != nil {
+		t.Fatal(err)
+	}
+	var out pump
+	if err := gos7logo.ReadStruct(ctx, c, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out != in {
+		t.Errorf("expected %+v, got %+v", in, out)
+	}
+	if c.calls != 2 {
+		t.Errorf("expected one batch call per struct, got %d calls", c.calls)
+	}
+
+	var bad struct {
+		Speed float32 `logo:"VW12"`
+	}
+	var fieldErr *gos7logo.FieldError
+	if err := gos7logo.ReadStruct(ctx, c, &bad); !errors.As(err, &fieldErr) || fieldErr.Field != "Speed" {
+		t.Errorf("expected field error for Speed, got %v", err)
+	}
+}
+
+// failClient fails one address like a PLC rejecting a single item.
+type failClient struct {
+	err error
+	memClient
+	addr gos7logo.VmAddr
+}
+
+func (c *failClient) ReadManyContext(ctx context.Context, addrs ...gos7logo.VmAddr) ([]gos7logo.VmAddrResult, error) {
+	results, err := c.memClient.ReadManyContext(ctx, addrs...)
+	for i := range results {
+		if addrs[i] == c.addr {
+			results[i].Err = c.err
+		}
+	}
+	return results, err
+}
+
+func (c *failClient) WriteManyContext(ctx context.Context, args ...gos7logo.VmAddrValue) error {
+	for _, arg := range args {
+		if arg.VmAddr == c.addr {
+			return &gos7logo.WriteError{Err: c.err, Addrs: []gos7logo.VmAddr{arg.VmAddr}}
+		}
+	}
+	return c.memClient.WriteManyContext(ctx, args...)
+}
+
+func TestStructFieldErrors(t *testing.T) {
+	ctx := context.Background()
+	rejected := errors.New("item rejected")
+	c := &failClient{
+		memClient: memClient{values: map[gos7logo.VmAddr]uint32{gos7logo.NewVmAddr(gos7logo.Int16, 12, 0): 7}},
+		err:       rejected,
+		addr:      gos7logo.NewVmAddr(gos7logo.DWord, 20, 0),
+	}
+	var fieldErr *gos7logo.FieldError
+	if err := gos7logo.WriteStruct(ctx, c, &pump{}); !errors.As(err, &fieldErr) || fieldErr.Field != "Hours" || !errors.Is(err, rejected) {
+		t.Errorf("expected write error for Hours, got %v", err)
+	}
+
+	// a failed read leaves the struct untouched
+	p := pump{Name: "p1", Speed: 3}
+	if err := gos7logo.ReadStruct(ctx, c, &p); !errors.As(err, &fieldErr) || fieldErr.Field != "Hours" {
+		t.Errorf("expected read error for Hours, got %v", err)
+	}
+	if p != (pump{Name: "p1", Speed: 3}) {
+		t.Errorf("expected struct unchanged after failed read, got %+v", p)
+	}
+}
@@ -0,0 +1,133 @@
+package test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+	"github.com/axon-expert/gos7-logo-client/logosim"
+)
+
+// capturedPacket is a TCP segment read back from a pcapng capture.
+type capturedPacket struct {
+	payload          []byte
+	seq, ack         uint32
+	srcPort, dstPort uint16
+	flags            byte
+}
+
+// readPcapng checks the section and interface blocks of a capture and
+// returns its packets.
+func readPcapng(t *testing.T, data []byte) []capturedPacket {
+	t.Helper()
+	le := binary.LittleEndian
+	if len(data) < 48 || le.Uint32(data) != 0x0A0D0D0A || le.Uint32(data[8:]) != 0x1A2B3C4D {
+		t.Fatalf("expected pcapng section header, got % x", data[:min(len(data), 12)])
+	}
+	data = data[le.Uint32(data[4:]):]
+	if le.Uint32(data) != 1 || le.Uint16(data[8:]) != 101 {
+		t.Fatalf("expected raw IP interface, got % x", data[:12])
+	}
+	data = data[le.Uint32(data[4:]):]
+
+	var packets []capturedPacket
+	for len(data) > 0 {
+		length := le.Uint32(data[4:])
+		if le.Uint32(data) != 6 || le.Uint32(data[length-4:]) != length {
+			t.Fatalf("expected enhanced packet block, got % x", data[:8])
+		}
+		ip := data[28 : 28+le.Uint32(data[20:])]
+		data = data[length:]
+
+		if ip[0] != 0x45 || ip[9] != 6 || int(binary.BigEndian.Uint16(ip[2:])) != len(ip) || checksum(ip[:20]) != 0 {
+			t.Fatalf("expected valid IPv4 header, got % x", ip[:20])
+		}
+		tcp := ip[20:]
+		pseudo := append(append([]byte{}, ip[12:20]...), 0, 6, byte(len(tcp)>>8), byte(len(tcp)))
+		if checksum(append(pseudo, tcp...)) != 0 {
+			t.Errorf("expected valid TCP checksum in % x", tcp[:20])
+		}
+		packets = append(packets, capturedPacket{
+			srcPort: binary.BigEndian.Uint16(tcp),
+			dstPort: binary.BigEndian.Uint16(tcp[2:]),
+			seq:     binary.BigEndian.Uint32(tcp[4:]),
+			ack:     binary.BigEndian.Uint32(tcp[8:]),
+			flags:   tcp[13],
+			payload: tcp[20:]})
+	}
+	return packets
+}
+
+// checksum returns the one's complement sum of b, 0 for a valid checksum.
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i < len(b); i += 2 {
+		sum += uint32(b[i]) << 8
+		if i+1 < len(b) {
+			sum += uint32(b[i+1])
+		}
+	}
+	for sum > 0xFFFF {
+		sum = sum>>16 + sum&0xFFFF
+	}
+	return ^uint16(sum)
+}
+
+func TestCapture(t *testing.T) {
+	sim := startSim(t, logosim.Config{})
+	if err := sim.WriteVM(5, []byte{0x2A}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	capture, err := gos7logo.NewCapture(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()}, gos7logo.WithCapture(capture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Read(gos7logo.NewVmAddr(gos7logo.Byte, 5, 0)); err != nil || v != 0x2A {
+		t.Fatalf("expected 0x2A, got %d %v", v, err)
+	}
+	if err := c.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	if capture.Err() != nil {
+		t.Fatal(capture.Err())
+	}
+
+	packets := readPcapng(t, buf.Bytes())
+	// handshake, COTP connect, S7 setup, read, FIN exchange
+	if len(packets) != 3+6+3 {
+		t.Fatalf("expected 12 packets, got %d", len(packets))
+	}
+	const syn, ack, fin = 0x02, 0x10, 0x01
+	if packets[0].flags != syn || packets[1].flags != syn|ack || packets[11].flags != ack || packets[9].flags&fin == 0 {
+		t.Errorf("expected handshake and FIN, got flags %x %x ... %x %x", packets[0].flags, packets[1].flags, packets[9].flags, packets[11].flags)
+	}
+	client := packets[0].srcPort
+	next := map[uint16]uint32{}
+	for i, p := range packets {
+		if seq, ok := next[p.srcPort]; ok && p.seq != seq {
+			t.Errorf("packet %d: expected seq %d, got %d", i, seq, p.seq)
+		}
+		next[p.srcPort] = p.seq + uint32(len(p.payload))
+		if p.flags&(syn|fin) != 0 {
+			next[p.srcPort]++
+		}
+		if p.flags&ack != 0 && p.ack != next[p.dstPort] {
+			t.Errorf("packet %d: expected ack %d, got %d", i, next[p.dstPort], p.ack)
+		}
+	}
+	for i, p := range packets[3:9] {
+		if len(p.payload) < 7 || p.payload[0] != 3 || (p.srcPort == client) != (i%2 == 0) {
+			t.Errorf("packet %d: expected alternating TPKT requests and responses, got % x", i+3, p.payload)
+		}
+	}
+	// COTP connection request, S7 read var response with the value
+	if packets[3].payload[5] != 0xE0 || packets[8].payload[len(packets[8].payload)-1] != 0x2A {
+		t.Errorf("expected connection request and read response, got % x / % x", packets[3].payload, packets[8].payload)
+	}
+}
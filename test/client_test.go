@@ -1,21 +1,39 @@
 package test
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 	"strconv"
 	"testing"
 
 	gos7logo "github.com/axon-expert/gos7-logo-client"
+	"github.com/axon-expert/gos7-logo-client/logosim"
 )
 
 var client gos7logo.Client
 
+// TestMain runs the suite against the simulator, or against the LOGO! at
+// GOS7LOGO_TEST_ADDR when it is set, e.g. `192.168.0.3:102`.
 func TestMain(m *testing.M) {
-	cl, err := gos7logo.NewClient("localhost:102", 0, 1, 0x100, 0x200)
+	addr := os.Getenv("GOS7LOGO_TEST_ADDR")
+	var sim *logosim.Server
+	if addr == "" {
+		var err error
+		sim, err = logosim.Listen(logosim.Config{LocalTSAP: 0x100, RemoteTSAP: 0x200})
+		if err != nil {
+			fmt.Printf("failed start simulator: %s\n", err)
+			os.Exit(1)
+		}
+		addr = sim.Addr()
+	}
+	cl, err := gos7logo.NewClient(addr, 0, 1, 0x100, 0x200)
 	if err != nil {
 		fmt.Printf("failed connect: %s\n", err)
+		os.Exit(1)
 	}
 	client = cl
 
@@ -23,6 +41,9 @@ func TestMain(m *testing.M) {
 	if err := client.Disconnect(); err != nil {
 		fmt.Printf("failed to disconnect: %s\n", err)
 	}
+	if sim != nil {
+		_ = sim.Close()
+	}
 	os.Exit(code)
 }
 
@@ -83,6 +104,14 @@ func TestClientWriteManyRead(t *testing.T) {
 	}
 }
 
+func vmAddrsOf(vals []gos7logo.VmAddrValue) []gos7logo.VmAddr {
+	addrs := make([]gos7logo.VmAddr, 0, len(vals))
+	for _, val := range vals {
+		addrs = append(addrs, val.VmAddr)
+	}
+	return addrs
+}
+
 func writeReadTest(t *testing.T, vmAddr string, value uint32) {
 	addr, err := gos7logo.NewVmAddrFromString(vmAddr)
 	if err != nil {
@@ -108,3 +137,201 @@ func writeReadTest(t *testing.T, vmAddr string, value uint32) {
 		t.Errorf("write and read values not equals for %s : %s != %s", vmAddr, strconv.Itoa(int(value)), strconv.Itoa(int(v)))
 	}
 }
+
+func TestClientTypedWriteRead(t *testing.T) {
+	int16Addr := gos7logo.NewVmAddr(gos7logo.Int16, 40, 0)
+	if err := client.WriteInt16(int16Addr, -125); err != nil {
+		t.Fatal(err)
+	}
+	i16, err := client.ReadInt16(int16Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i16 != -125 {
+		t.Errorf("write and read int16 values not equals: -125 != %d", i16)
+	}
+
+	int32Addr := gos7logo.NewVmAddr(gos7logo.Int32, 44, 0)
+	if err := client.WriteInt32(int32Addr, -70000); err != nil {
+		t.Fatal(err)
+	}
+	i32, err := client.ReadInt32(int32Addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i32 != -70000 {
+		t.Errorf("write and read int32 values not equals: -70000 != %d", i32)
+	}
+
+	floatAddr := gos7logo.NewVmAddr(gos7logo.Float32, 48, 0)
+	if err := client.WriteFloat32(floatAddr, -12.5); err != nil {
+		t.Fatal(err)
+	}
+	f32, err := client.ReadFloat32(floatAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f32 != -12.5 {
+		t.Errorf("write and read float32 values not equals: -12.5 != %v", f32)
+	}
+
+	if _, err := client.ReadInt16(floatAddr); err == nil {
+		t.Errorf("expected size mismatch error reading float address as int16")
+	}
+}
+
+// requestLog is a transporter recording the requests it forwards.
+type requestLog struct {
+	gos7logo.Transporter
+	requests [][]byte
+}
+
+func (l *requestLog) Send(request []byte) ([]byte, error) {
+	l.requests = append(l.requests, append([]byte(nil), request...))
+	return l.Transporter.Send(request)
+}
+
+func TestClientBitWrite(t *testing.T) {
+	sim := startSim(t, logosim.Config{})
+	if err := sim.WriteVM(60, []byte{0b1010_0101}); err != nil {
+		t.Fatal(err)
+	}
+	log := &requestLog{}
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()},
+		gos7logo.WithTransportWrapper(func(inner gos7logo.Transporter) gos7logo.Transporter {
+			log.Transporter = inner
+			return log
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	if err := c.Write(gos7logo.NewVmAddr(gos7logo.Bit, 60, 4), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Write(gos7logo.NewVmAddr(gos7logo.Bit, 60, 0), 0); err != nil {
+		t.Fatal(err)
+	}
+	// one write var job per bit with bit transport size, no read of the byte
+	if len(log.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(log.requests))
+	}
+	for _, req := range log.requests {
+		if req[17] != 0x05 || req[22] != 0x01 {
+			t.Errorf("expected bit write var job, got % x", req)
+		}
+	}
+	if b, _ := sim.ReadVM(60, 1); b[0] != 0b1011_0100 {
+		t.Errorf("expected only V60.4 and V60.0 to change, got %08b", b[0])
+	}
+}
+
+// the uint32 API carries the bit patterns of signed and float values
+func TestClientUint32Encoding(t *testing.T) {
+	int16Addr := gos7logo.NewVmAddr(gos7logo.Int16, 52, 0)
+	floatAddr := gos7logo.NewVmAddr(gos7logo.Float32, 54, 0)
+	if err := client.WriteInt16(int16Addr, -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.WriteFloat32(floatAddr, 21.25); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := client.Read(int16Addr); err != nil || v != 0xFFFF {
+		t.Errorf("expected int16 -1 as 0xFFFF, got %#x %v", v, err)
+	}
+	if v, err := client.Read(floatAddr); err != nil || math.Float32frombits(v) != 21.25 {
+		t.Errorf("expected 21.25, got %v %v", math.Float32frombits(v), err)
+	}
+
+	results, err := client.ReadMany(int16Addr, floatAddr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int16(results[0].Value) != -1 || math.Float32frombits(results[1].Value) != 21.25 {
+		t.Errorf("expected -1 and 21.25 from ReadMany, got %#x %#x", results[0].Value, results[1].Value)
+	}
+
+	if err := client.WriteMany(
+		gos7logo.VmAddrValue{VmAddr: int16Addr, Value: 0xFF85},
+		gos7logo.VmAddrValue{VmAddr: floatAddr, Value: math.Float32bits(-0.5)},
+	); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := client.ReadInt16(int16Addr); err != nil || v != -123 {
+		t.Errorf("expected -123, got %d %v", v, err)
+	}
+	if v, err := client.ReadFloat32(floatAddr); err != nil || v != -0.5 {
+		t.Errorf("expected -0.5, got %v %v", v, err)
+	}
+
+	// Real keeps converting the integer value
+	realAddr := gos7logo.NewVmAddr(gos7logo.Real, 54, 0)
+	if err := client.Write(realAddr, 25); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := client.ReadFloat32(realAddr); err != nil || v != 25 {
+		t.Errorf("expected 25.0 written through Real, got %v %v", v, err)
+	}
+	if err := client.WriteFloat32(realAddr, 25.75); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := client.Read(realAddr); err != nil || v != 25 {
+		t.Errorf("expected Real 25.75 read as 25, got %d %v", v, err)
+	}
+}
+
+func TestClientContextCanceled(t *testing.T) {
+	addr, err := gos7logo.NewVmAddrFromString("VW31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.ReadContext(ctx, addr); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	// the session must stay usable after the aborted call
+	if _, err := client.ReadContext(context.Background(), addr); err != nil {
+		t.Errorf("failed read after canceled call: %s", err)
+	}
+}
+
+func TestClientReadMany(t *testing.T) {
+	var vmAddrVals []gos7logo.VmAddrValue
+	for i := 0; i < 40; i++ {
+		addr := gos7logo.NewVmAddr(gos7logo.Word, uint32(100+i*10), 0)
+		vmAddrVals = append(vmAddrVals, gos7logo.VmAddrValue{VmAddr: addr, Value: uint32(rand.Intn(1000))})
+	}
+	for _, val := range vmAddrVals {
+		if err := client.Write(val.VmAddr, val.Value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := client.ReadMany(vmAddrsOf(vmAddrVals)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != len(vmAddrVals) {
+		t.Fatalf("expected %d results, got %d", len(vmAddrVals), len(results))
+	}
+	for i, res := range results {
+		if res.Err != nil {
+			t.Errorf("failed read VW%d: %s", res.VmAddr.Byte, res.Err)
+			continue
+		}
+		if res.Value != vmAddrVals[i].Value {
+			t.Errorf("write and read values not equals for VW%d: %d != %d", res.VmAddr.Byte, vmAddrVals[i].Value, res.Value)
+		}
+	}
+
+	outOfRange := gos7logo.NewVmAddr(gos7logo.Byte, 900, 0)
+	results, err = client.ReadMany(vmAddrVals[0].VmAddr, outOfRange)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil || !errors.Is(results[1].Err, gos7logo.ErrAddressOutOfRange) {
+		t.Errorf("expected an error for V900 only, got %v / %v", results[0].Err, results[1].Err)
+	}
+}
@@ -0,0 +1,62 @@
+package test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+func TestDeadbandFilter(t *testing.T) {
+	ai := gos7logo.NewVmAddr(gos7logo.Word, 1032, 0)
+	filter := gos7logo.NewDeadbandFilter()
+	if err := filter.Set(ai, gos7logo.Deadband{
+		Percent:     1, // 10 counts of 0..1000
+		MinInterval: time.Second,
+		MaxSilence:  time.Minute,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	steps := []struct {
+		after   time.Duration
+		value   uint32
+		publish bool
+	}{
+		{0, 500, true},
+		{2 * time.Second, 507, false},            // within deadband
+		{3 * time.Second, 520, true},             // left deadband
+		{3500 * time.Millisecond, 600, false},    // too soon
+		{4 * time.Second, 600, true},             // minimum interval passed
+		{4*time.Second + time.Minute, 600, true}, // republished after silence
+	}
+	for _, step := range steps {
+		if got := filter.Update(ai, step.value, start.Add(step.after)); got != step.publish {
+			t.Errorf("%d after %s: expected publish %v", step.value, step.after, step.publish)
+		}
+	}
+
+	if err := filter.Set(gos7logo.NewVmAddr(gos7logo.Bit, 1, 2), gos7logo.Deadband{Absolute: 1}); err == nil {
+		t.Errorf("expected deadband on bit address to fail")
+	}
+}
+
+func TestDeadbandFilterFloat32(t *testing.T) {
+	addr := gos7logo.NewVmAddr(gos7logo.Float32, 20, 0)
+	filter := gos7logo.NewDeadbandFilter()
+	if err := filter.Set(addr, gos7logo.Deadband{Absolute: 0.5}); err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	steps := []struct {
+		value   float32
+		publish bool
+	}{{20, true}, {20.1, false}, {20.4, false}, {20.6, true}, {-1, true}}
+	for _, step := range steps {
+		if got := filter.Update(addr, math.Float32bits(step.value), now); got != step.publish {
+			t.Errorf("%v: expected publish %v", step.value, step.publish)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+// the fake has to be usable wherever the real client is
+var _ gos7logo.Client = (*gos7logo.FakeClient)(nil)
+
+func TestFakeClientEncoding(t *testing.T) {
+	fake := gos7logo.NewFakeClient(gos7logo.DefaultModel)
+	if err := fake.Write(gos7logo.NewVmAddr(gos7logo.DWord, 0, 0), 0x01020304); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.Write(gos7logo.NewVmAddr(gos7logo.Word, 4, 0), 0xABCD); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.Write(gos7logo.NewVmAddr(gos7logo.Bit, 6, 3), 1); err != nil {
+		t.Fatal(err)
+	}
+	want := []byte{1, 2, 3, 4, 0xAB, 0xCD, 0b0000_1000}
+	if got := fake.Bytes(0, len(want)); string(got) != string(want) {
+		t.Errorf("expected image % x, got % x", want, got)
+	}
+
+	if err := fake.WriteInt16(gos7logo.NewVmAddr(gos7logo.Int16, 10, 0), -2); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := fake.ReadInt16(gos7logo.NewVmAddr(gos7logo.Word, 10, 0)); err != nil || v != -2 {
+		t.Errorf("expected -2, got %d %v", v, err)
+	}
+	if err := fake.WriteFloat32(gos7logo.NewVmAddr(gos7logo.Float32, 12, 0), 21.5); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := fake.ReadFloat32(gos7logo.NewVmAddr(gos7logo.DWord, 12, 0)); err != nil || v != 21.5 {
+		t.Errorf("expected 21.5, got %v %v", v, err)
+	}
+	if v, err := fake.Read(gos7logo.NewVmAddr(gos7logo.Int16, 10, 0)); err != nil || v != 0xFFFE {
+		t.Errorf("expected int16 -2 as 0xFFFE, got %#x %v", v, err)
+	}
+	results, err := fake.ReadMany(gos7logo.NewVmAddr(gos7logo.Float32, 12, 0))
+	if err != nil || math.Float32frombits(results[0].Value) != 21.5 {
+		t.Errorf("expected 21.5 from ReadMany, got %+v %v", results, err)
+	}
+	if err := fake.Write(gos7logo.NewVmAddr(gos7logo.Byte, 900, 0), 1); !errors.Is(err, gos7logo.ErrAddressOutOfRange) {
+		t.Errorf("expected out of range error, got %v", err)
+	}
+}
+
+func TestFakeClientWrites(t *testing.T) {
+	fake := gos7logo.NewFakeClient(gos7logo.DefaultModel)
+	a := gos7logo.NewVmAddr(gos7logo.Byte, 1, 0)
+	b := gos7logo.NewVmAddr(gos7logo.Word, 2, 0)
+	if err := fake.Write(a, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := fake.WriteMany(gos7logo.VmAddrValue{VmAddr: b, Value: 7}, gos7logo.VmAddrValue{VmAddr: a, Value: 6}); err != nil {
+		t.Fatal(err)
+	}
+	writes := fake.Writes()
+	if len(writes) != 3 {
+		t.Fatalf("expected 3 writes, got %d", len(writes))
+	}
+	order := []struct {
+		addr  gos7logo.VmAddr
+		value uint32
+		call  int
+	}{{a, 5, 1}, {b, 7, 2}, {a, 6, 2}}
+	for i, w := range order {
+		if writes[i].VmAddr != w.addr || writes[i].Value != w.value || writes[i].Call != w.call {
+			t.Errorf("write %d: expected %+v, got %+v", i, w, writes[i])
+		}
+	}
+
+	injected := errors.New("injected")
+	fake.FailWrite(gos7logo.NewVmAddr(gos7logo.Byte, 3, 0), injected)
+	fake.ResetWrites()
+	err := fake.WriteMany(gos7logo.VmAddrValue{VmAddr: a, Value: 1}, gos7logo.VmAddrValue{VmAddr: b, Value: 1})
+	var writeErr *gos7logo.WriteError
+	if !errors.Is(err, injected) || !errors.As(err, &writeErr) || writeErr.Addrs[0] != b {
+		t.Errorf("expected injected error for VW2, got %v", err)
+	}
+	if len(fake.Writes()) != 0 {
+		t.Errorf("expected failed WriteMany to write nothing")
+	}
+
+	fake.FailRead(b, injected)
+	results, err := fake.ReadMany(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Err != nil || results[0].Value != 6 || !errors.Is(results[1].Err, injected) {
+		t.Errorf("expected V1 = 6 and an error for VW2, got %+v", results)
+	}
+	fake.ClearErrors()
+	if v, err := fake.Read(b); err != nil || v != 7 {
+		t.Errorf("expected 7 after clearing errors, got %d %v", v, err)
+	}
+}
+
+func TestFakeClientState(t *testing.T) {
+	fake := gos7logo.NewFakeClient(gos7logo.DefaultModel)
+	var changes []gos7logo.StateChange
+	unsubscribe := fake.SubscribeState(func(ch gos7logo.StateChange) { changes = append(changes, ch) })
+	defer unsubscribe()
+	addr := gos7logo.NewVmAddr(gos7logo.Byte, 1, 0)
+
+	broken := errors.New("connection reset")
+	fake.SetState(gos7logo.StateDegraded, broken)
+	if _, err := fake.Read(addr); !errors.Is(err, broken) {
+		t.Errorf("expected reads to fail while degraded, got %v", err)
+	}
+	if h := fake.Health(); h.State != gos7logo.StateDegraded || h.LastError != broken || h.PDULength != 0 {
+		t.Errorf("expected degraded health with the error, got %+v", h)
+	}
+	fake.SetState(gos7logo.StateConnecting, nil)
+	fake.SetState(gos7logo.StateConnected, nil)
+	if _, err := fake.Read(addr); err != nil {
+		t.Errorf("expected reads to work after reconnecting, got %v", err)
+	}
+	if err := fake.Disconnect(); err != nil {
+		t.Fatal(err)
+	}
+	_ = fake.Disconnect()
+
+	want := []struct{ from, to gos7logo.ConnState }{
+		{gos7logo.StateConnected, gos7logo.StateDegraded},
+		{gos7logo.StateDegraded, gos7logo.StateConnecting},
+		{gos7logo.StateConnecting, gos7logo.StateConnected},
+		{gos7logo.StateConnected, gos7logo.StateClosed},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("expected %d state changes, got %+v", len(want), changes)
+	}
+	for i, w := range want {
+		if changes[i].From != w.from || changes[i].To != w.to {
+			t.Errorf("change %d: expected %s -> %s, got %s -> %s", i, w.from, w.to, changes[i].From, changes[i].To)
+		}
+	}
+	if changes[0].Err != broken || changes[2].PDULength == 0 {
+		t.Errorf("expected the error on degrading and a PDU length on connecting, got %+v", changes)
+	}
+}
@@ -0,0 +1,159 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+	gos7patch "github.com/axon-expert/gos7-logo-client/gos7-patch"
+	"github.com/axon-expert/gos7-logo-client/logosim"
+)
+
+// faultClient connects to a fresh simulator through a FaultTransporter
+// running the given plan. The plan starts after the connection is set up.
+func faultClient(t *testing.T, plan gos7patch.FaultPlan) (gos7logo.Client, *gos7patch.FaultTransporter, *logosim.Server) {
+	t.Helper()
+	sim := startSim(t, logosim.Config{})
+	var faults *gos7patch.FaultTransporter
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()},
+		gos7logo.WithReconnect(gos7logo.DefaultReconnectPolicy()),
+		gos7logo.WithTransportWrapper(func(inner gos7logo.Transporter) gos7logo.Transporter {
+			faults = gos7patch.NewFaultTransporter(inner, plan)
+			return faults
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = c.Disconnect() })
+	return c, faults, sim
+}
+
+func TestFaultDisconnectReconnects(t *testing.T) {
+	c, faults, sim := faultClient(t, gos7patch.NewFaultScript(gos7patch.Fault{Kind: gos7patch.FaultDisconnect}))
+	if err := sim.WriteVM(3, []byte{42}); err != nil {
+		t.Fatal(err)
+	}
+	var trace gos7logo.RetryTrace
+	v, err := c.ReadContext(gos7logo.WithRetryTrace(context.Background(), &trace), gos7logo.NewVmAddr(gos7logo.Byte, 3, 0))
+	if err != nil || v != 42 {
+		t.Fatalf("expected 42 after reconnect, got %d %v", v, err)
+	}
+	if trace.Reconnects != 1 || len(faults.Injected()) != 1 {
+		t.Errorf("expected one injected disconnect and one reconnect, got %d / %d", len(faults.Injected()), trace.Reconnects)
+	}
+}
+
+func TestFaultDropResponse(t *testing.T) {
+	c, _, sim := faultClient(t, gos7patch.NewFaultScript(gos7patch.Fault{Kind: gos7patch.FaultDropResponse}))
+	addr := gos7logo.NewVmAddr(gos7logo.Byte, 4, 0)
+	if err := c.Write(addr, 9); !errors.Is(err, gos7patch.ErrFaultInjected) {
+		t.Fatalf("expected injected error, got %v", err)
+	}
+	// the write reached the PLC before the connection dropped
+	if b, _ := sim.ReadVM(4, 1); b[0] != 9 {
+		t.Errorf("expected V4 written, got %d", b[0])
+	}
+	if c.Health().State != gos7logo.StateDegraded {
+		t.Errorf("expected degraded connection, got %s", c.Health().State)
+	}
+	if v, err := c.Read(addr); err != nil || v != 9 {
+		t.Errorf("expected 9 after reconnect, got %d %v", v, err)
+	}
+}
+
+func TestFaultBrokenResponses(t *testing.T) {
+	c, _, _ := faultClient(t, gos7patch.NewFaultScript(
+		gos7patch.Fault{Kind: gos7patch.FaultTruncate, Length: 20},
+		gos7patch.Fault{Kind: gos7patch.FaultCorrupt, Offset: 23, Mask: 0x80},
+		gos7patch.Fault{Kind: gos7patch.FaultS7Error, ErrorClass: 0x81, ErrorCode: 0x04},
+		gos7patch.Fault{Kind: gos7patch.FaultLatency, Delay: time.Second},
+	))
+	addrs := []gos7logo.VmAddr{gos7logo.NewVmAddr(gos7logo.Word, 10, 0), gos7logo.NewVmAddr(gos7logo.Word, 400, 0)}
+
+	if _, err := c.ReadMany(addrs...); err == nil {
+		t.Errorf("expected error for truncated response")
+	}
+	// item length 0x80 bits longer than the response
+	if _, err := c.ReadMany(addrs...); err == nil {
+		t.Errorf("expected error for corrupt response")
+	}
+	results, err := c.ReadMany(addrs...)
+	var clientErr *gos7patch.ClientError
+	if !errors.As(err, &clientErr) || results[0].Quality != gos7logo.QualityConfigError {
+		t.Errorf("expected refused job, got %v %s", err, results[0].Quality)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := c.ReadContext(ctx, addrs[0]); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+	if _, err := c.ReadMany(addrs...); err != nil {
+		t.Errorf("expected client to recover after the script, got %v", err)
+	}
+}
+
+func TestRandomFaultsDeterministic(t *testing.T) {
+	faults := []gos7patch.Fault{
+		{Kind: gos7patch.FaultDisconnect, Probability: 0.2},
+		{Kind: gos7patch.FaultS7Error, Probability: 0.3},
+	}
+	a := gos7patch.NewRandomFaults(7, faults...)
+	b := gos7patch.NewRandomFaults(7, faults...)
+	counts := map[gos7patch.FaultKind]int{}
+	for range 1000 {
+		fa, fb := a.Next(nil), b.Next(nil)
+		if fa.Kind != fb.Kind {
+			t.Fatalf("expected the same faults for the same seed")
+		}
+		counts[fa.Kind]++
+	}
+	if counts[gos7patch.FaultDisconnect] < 150 || counts[gos7patch.FaultS7Error] < 250 || counts[gos7patch.FaultNone] < 450 {
+		t.Errorf("unexpected fault distribution %v", counts)
+	}
+}
+
+// run with -race: requests size their jobs by the PDU length while other
+// requests reconnect
+func TestPDULengthDuringReconnect(t *testing.T) {
+	c, _, _ := faultClient(t, gos7patch.NewRandomFaults(1, gos7patch.Fault{Kind: gos7patch.FaultDisconnect, Probability: 0.3}))
+	addrs := []gos7logo.VmAddr{gos7logo.NewVmAddr(gos7logo.Word, 0, 0), gos7logo.NewVmAddr(gos7logo.Word, 600, 0)}
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				if _, err := c.ReadMany(addrs...); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestFaultRefusedWriteJob(t *testing.T) {
+	c, _, _ := faultClient(t, gos7patch.NewFaultScript(gos7patch.Fault{Kind: gos7patch.FaultS7Error, ErrorClass: 0x81, ErrorCode: 0x04}))
+	// two items of one multi write job
+	speed, level := gos7logo.NewVmAddr(gos7logo.Word, 12, 0), gos7logo.NewVmAddr(gos7logo.Byte, 400, 0)
+	err := c.WriteMany(gos7logo.VmAddrValue{VmAddr: speed, Value: 1}, gos7logo.VmAddrValue{VmAddr: level, Value: 2})
+	var writeErr *gos7logo.WriteError
+	var clientErr *gos7patch.ClientError
+	if !errors.As(err, &writeErr) || !errors.As(err, &clientErr) || len(writeErr.Addrs) != 2 || writeErr.Addrs[0] != speed {
+		t.Fatalf("expected refused job with both addresses, got %v", err)
+	}
+
+	var target struct {
+		Speed int16 `logo:"VW12"`
+		Level uint8 `logo:"V400"`
+	}
+	c, _, _ = faultClient(t, gos7patch.NewFaultScript(gos7patch.Fault{Kind: gos7patch.FaultS7Error, ErrorClass: 0x81, ErrorCode: 0x04}))
+	var fieldErr *gos7logo.FieldError
+	if err := gos7logo.WriteStruct(context.Background(), c, &target); !errors.As(err, &fieldErr) || fieldErr.Field != "Speed" {
+		t.Errorf("expected field error for Speed, got %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+	"github.com/axon-expert/gos7-logo-client/logosim"
+)
+
+func startSim(t *testing.T, cfg logosim.Config) *logosim.Server {
+	t.Helper()
+	sim, err := logosim.Listen(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sim.Close() })
+	return sim
+}
+
+func TestSimulatorTSAP(t *testing.T) {
+	sim := startSim(t, logosim.Config{LocalTSAP: 0x100, RemoteTSAP: 0x200})
+	if _, err := gos7logo.NewClient(sim.Addr(), 0, 1, 0x100, 0x300); err == nil {
+		t.Errorf("expected connection with wrong TSAP to be refused")
+	}
+	c, err := gos7logo.NewClient(sim.Addr(), 0, 1, 0x100, 0x200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+}
+
+func TestSimulatorImage(t *testing.T) {
+	sim := startSim(t, logosim.Config{PDULength: 64})
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr(), LocalTSAP: 0x100, RemoteTSAP: 0x200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+	if pdu := c.Health().PDULength; pdu != 64 {
+		t.Errorf("expected negotiated PDU length 64, got %d", pdu)
+	}
+
+	if err := sim.WriteVM(10, []byte{0x12, 0x34, 0b0000_0100}); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := c.Read(gos7logo.NewVmAddr(gos7logo.Word, 10, 0)); err != nil || v != 0x1234 {
+		t.Errorf("expected 0x1234, got %#x %v", v, err)
+	}
+	if v, err := c.Read(gos7logo.NewVmAddr(gos7logo.Bit, 12, 2)); err != nil || v != 1 {
+		t.Errorf("expected V12.2 set, got %d %v", v, err)
+	}
+	if err := c.Write(gos7logo.NewVmAddr(gos7logo.Bit, 12, 7), 1); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := sim.ReadVM(12, 1); err != nil || b[0] != 0b1000_0100 {
+		t.Errorf("expected only V12.7 to be added, got %08b %v", b, err)
+	}
+
+	// the small PDU splits the batch into several jobs
+	var addrs []gos7logo.VmAddr
+	for i := range 30 {
+		addrs = append(addrs, gos7logo.NewVmAddr(gos7logo.DWord, uint32(100+i*8), 0))
+	}
+	results, err := c.ReadManyContext(context.Background(), addrs...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		if res.Err != nil {
+			t.Errorf("failed read VD%d: %s", res.VmAddr.Byte, res.Err)
+		}
+	}
+}
+
+func TestSimulatorReconnect(t *testing.T) {
+	sim := startSim(t, logosim.Config{})
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr(), LocalTSAP: 0x100, RemoteTSAP: 0x200},
+		gos7logo.WithReconnect(gos7logo.DefaultReconnectPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	addr := gos7logo.NewVmAddr(gos7logo.Byte, 5, 0)
+	if err := c.Write(addr, 7); err != nil {
+		t.Fatal(err)
+	}
+	sim.DropConnections()
+	var trace gos7logo.RetryTrace
+	v, err := c.ReadContext(gos7logo.WithRetryTrace(context.Background(), &trace), addr)
+	if err != nil || v != 7 {
+		t.Errorf("expected 7 after reconnect, got %d %v", v, err)
+	}
+	if trace.Reconnects == 0 {
+		t.Errorf("expected a reconnect")
+	}
+}
@@ -0,0 +1,52 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+func TestImportLogoSoft(t *testing.T) {
+	export := "\ufeffNr.;Baustein;Parameter;Typ;Adresse\n" +
+		"1;B001;Current value;Word;0\n" +
+		"2;B002;Q;Bit;2.3\n" +
+		"3;b003;Counter value;DWord;VD4\n" +
+		"4;B004;Ax;Int;8\n"
+	defs, err := gos7logo.ImportLogoSoft(strings.NewReader(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []gos7logo.TagDef{
+		{Name: "B001_current_value", Addr: "VW0", Description: "B001 Current value"},
+		{Name: "B002_q", Addr: "V2.3", Description: "B002 Q"},
+		{Name: "B003_counter_value", Addr: "VD4", Description: "b003 Counter value"},
+		{Name: "B004_ax", Addr: "VW8", Type: "int16", Description: "B004 Ax"},
+	}
+	if len(defs) != len(want) {
+		t.Fatalf("expected %d tags, got %+v", len(want), defs)
+	}
+	for i := range want {
+		if defs[i] != want[i] {
+			t.Errorf("row %d: expected %+v, got %+v", i+1, want[i], defs[i])
+		}
+	}
+	if _, err := gos7logo.NewRegistry(defs); err != nil {
+		t.Errorf("imported tags do not register: %s", err)
+	}
+
+	tabs := "Block\tParameter\tType\tAddress\nB010\tOn\tBit\t5\n"
+	if _, err := gos7logo.ImportLogoSoft(strings.NewReader(tabs)); err == nil {
+		t.Errorf("expected error for bit without bit number")
+	}
+	// V addresses have to agree with the type column
+	for _, row := range []string{"B011;Value;Word;V12", "B012;On;Bit;VW4", "B013;Total;DWord;VW4", "B014;Temp;Real;V8.1"} {
+		_, err := gos7logo.ImportLogoSoft(strings.NewReader("Block;Parameter;Type;Address\n" + row + "\n"))
+		if err == nil {
+			t.Errorf("expected type mismatch error for `%s`", row)
+		}
+	}
+	if _, err := gos7logo.ImportLogoSoft(strings.NewReader("Block;Parameter;Type;Address\nB015;Temp;Real;VD8\n")); err != nil {
+		t.Errorf("unexpected error for Real at VD8: %s", err)
+	}
+}
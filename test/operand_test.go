@@ -0,0 +1,114 @@
+package test
+
+import (
+	"errors"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+func TestNewVmAddrFromOperand(t *testing.T) {
+	cases := []struct {
+		operand string
+		want    gos7logo.DataType
+		byte    uint32
+		bit     uint8
+	}{
+		{"I1", gos7logo.Bit, 1024, 0},
+		{"I10", gos7logo.Bit, 1025, 1},
+		{"q20", gos7logo.Bit, 1066, 3},
+		{"M64", gos7logo.Bit, 1111, 7},
+		{"AI1", gos7logo.Word, 1032, 0},
+		{"AQ2", gos7logo.Word, 1074, 0},
+		{"AM64", gos7logo.Word, 1244, 0},
+		{"NI9", gos7logo.Bit, 1247, 0},
+		{"NQ1", gos7logo.Bit, 1390, 0},
+		{"C4", gos7logo.Bit, 1052, 3},
+		{"S2.1", gos7logo.Bit, 1057, 0},
+	}
+	for _, c := range cases {
+		addr, err := gos7logo.NewVmAddrFromOperand(c.operand)
+		if err != nil {
+			t.Errorf("failed parse `%s`: %s", c.operand, err)
+			continue
+		}
+		if addr.Type != c.want || addr.Byte != c.byte || addr.Bit != c.bit {
+			t.Errorf("`%s`: expected %v V%d.%d, got %v V%d.%d", c.operand, c.want, c.byte, c.bit, addr.Type, addr.Byte, addr.Bit)
+		}
+	}
+
+	for _, bad := range []string{"I0", "I25", "AQ9", "X1", "Q1.2", "VW10"} {
+		if _, err := gos7logo.NewVmAddrFromOperand(bad); err == nil {
+			t.Errorf("expected error for `%s`", bad)
+		}
+	}
+}
+
+func TestModelValidate(t *testing.T) {
+	v900 := gos7logo.NewVmAddr(gos7logo.Byte, 900, 0)
+	if err := gos7logo.Model0BA7.Validate(v900); !errors.Is(err, gos7logo.ErrAddressOutOfRange) {
+		t.Errorf("expected out of range error for V900, got %v", err)
+	}
+	vw848 := gos7logo.NewVmAddr(gos7logo.Word, 848, 0)
+	if err := gos7logo.Model0BA8.Validate(vw848); err != nil {
+		t.Errorf("unexpected error for VW848: %s", err)
+	}
+	vw849 := gos7logo.NewVmAddr(gos7logo.Word, 849, 0)
+	if err := gos7logo.Model0BA8.Validate(vw849); err == nil {
+		t.Errorf("expected error for VW849 crossing the user area")
+	}
+
+	ai1, err := gos7logo.Model0BA7.NewVmAddrFromOperand("AI1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ai1.Byte != 926 {
+		t.Errorf("expected 0BA7 AI1 at VW926, got VW%d", ai1.Byte)
+	}
+	if err := gos7logo.Model0BA7.Validate(ai1); err != nil {
+		t.Errorf("unexpected error for 0BA7 AI1: %s", err)
+	}
+	if err := gos7logo.Model0BA8.Validate(ai1); err == nil {
+		t.Errorf("expected 0BA7 AI1 address to be rejected by 0BA8")
+	}
+	if _, err := gos7logo.Model0BA7.NewVmAddrFromOperand("NI1"); err == nil {
+		t.Errorf("expected NI to be unsupported by 0BA7")
+	}
+}
+
+func TestModelLayouts(t *testing.T) {
+	models := []gos7logo.Model{gos7logo.Model0BA7, gos7logo.Model0BA8, gos7logo.Model8FS4}
+	cases := []struct {
+		valid []bool // per model
+		typ   gos7logo.DataType
+		byte  uint32
+		bit   uint8
+	}{
+		{[]bool{true, true, true}, gos7logo.Byte, 849, 0},
+		{[]bool{false, false, false}, gos7logo.Byte, 850, 0},
+		// 0BA7 digital inputs
+		{[]bool{true, false, false}, gos7logo.Bit, 923, 2},
+		// LOGO! 8 analog inputs and network outputs
+		{[]bool{false, true, true}, gos7logo.Word, 1032, 0},
+		{[]bool{false, true, true}, gos7logo.Bit, 1397, 7},
+		{[]bool{false, false, false}, gos7logo.Word, 1438, 0},
+	}
+	for _, c := range cases {
+		addr := gos7logo.NewVmAddr(c.typ, c.byte, c.bit)
+		for i, model := range models {
+			err := model.Validate(addr)
+			if c.valid[i] != (err == nil) {
+				t.Errorf("%s V%d: expected valid=%v, got %v", model, c.byte, c.valid[i], err)
+			}
+		}
+	}
+	for _, model := range models {
+		q1, err := model.NewVmAddrFromOperand("Q1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := model.Validate(q1); err != nil {
+			t.Errorf("%s: expected Q1 at V%d to be valid, got %v", model, q1.Byte, err)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+	"github.com/axon-expert/gos7-logo-client/logosim"
+)
+
+func TestNewClientWithOptInvalid(t *testing.T) {
+	cases := []gos7logo.ConnectOpt{
+		{},
+		{Addr: "localhost:102", Area: "MK"},
+		{Addr: "localhost:102", DBNumber: -1},
+	}
+	for _, opt := range cases {
+		if _, err := gos7logo.NewClientWithOpt(opt); err == nil {
+			t.Errorf("expected error for %+v", opt)
+		}
+	}
+}
+
+// logBuffer collects log output written from the idle close goroutine.
+type logBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestOptionsReachTransport(t *testing.T) {
+	sim := startSim(t, logosim.Config{LocalTSAP: 0x1100, RemoteTSAP: 0x0201, DBNumber: 2})
+	var logged logBuffer
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr(), Slot: 1},
+		// OP connection: remote TSAP 0x0201 from connection type, rack and slot
+		gos7logo.WithConnectionType(2),
+		gos7logo.WithTSAP(0x1100, 0),
+		gos7logo.WithPDUSize(128),
+		gos7logo.WithDBNumber(2),
+		gos7logo.WithModel(gos7logo.Model0BA7),
+		gos7logo.WithLogger(log.New(&logged, "", 0)),
+		gos7logo.WithIdleTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	if c.Health().PDULength != 128 {
+		t.Errorf("expected requested PDU length 128 to be negotiated, got %d", c.Health().PDULength)
+	}
+	if c.Model() != gos7logo.Model0BA7 {
+		t.Errorf("expected model 0BA7, got %s", c.Model())
+	}
+	if _, err := c.Read(gos7logo.NewVmAddr(gos7logo.Bit, 1024, 0)); !errors.Is(err, gos7logo.ErrAddressOutOfRange) {
+		t.Errorf("expected LOGO! 8 address to be rejected for 0BA7, got %v", err)
+	}
+	if err := sim.WriteVM(5, []byte{17}); err != nil {
+		t.Fatal(err)
+	}
+	// the simulator only serves DB2
+	if v, err := c.Read(gos7logo.NewVmAddr(gos7logo.Byte, 5, 0)); err != nil || v != 17 {
+		t.Errorf("expected 17 from DB2, got %d %v", v, err)
+	}
+	if !strings.Contains(logged.String(), "s7: sending") {
+		t.Errorf("expected logger to receive the exchanges, got %q", logged.String())
+	}
+	time.Sleep(150 * time.Millisecond)
+	if c.Health().State != gos7logo.StateDisconnected {
+		t.Errorf("expected connection closed by idle timeout, got %s", c.Health().State)
+	}
+}
+
+func TestOptionsTimeout(t *testing.T) {
+	// accepts connections but never answers
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	start := time.Now()
+	if _, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: ln.Addr().String()}, gos7logo.WithTimeout(50*time.Millisecond)); err == nil {
+		t.Fatal("expected connect to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected timeout after 50ms, took %s", elapsed)
+	}
+}
+
+func TestOptionsReconnect(t *testing.T) {
+	sim := startSim(t, logosim.Config{})
+	addr := gos7logo.NewVmAddr(gos7logo.Byte, 1, 0)
+
+	// without WithReconnect a broken connection is reported, not resent
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+	sim.DropConnections()
+	if _, err := c.Read(addr); err == nil {
+		t.Errorf("expected error on the dropped connection")
+	}
+	if _, err := c.Read(addr); err == nil {
+		t.Errorf("expected no reconnect without WithReconnect")
+	}
+
+	c, err = gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()}, gos7logo.WithReconnect(gos7logo.ReconnectPolicy{MaxAttempts: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+	sim.DropConnections()
+	if _, err := c.Read(addr); err != nil {
+		t.Errorf("expected read to be resent after reconnect, got %v", err)
+	}
+}
@@ -0,0 +1,90 @@
+package test
+
+import (
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+func TestReadPlanCoalescing(t *testing.T) {
+	addrs := []gos7logo.VmAddr{
+		gos7logo.NewVmAddr(gos7logo.Word, 10, 0),
+		gos7logo.NewVmAddr(gos7logo.Bit, 2, 4),
+		gos7logo.NewVmAddr(gos7logo.Byte, 800, 0),
+		gos7logo.NewVmAddr(gos7logo.DWord, 4, 0),
+	}
+	plan := gos7logo.NewReadPlan(addrs, 240)
+	if len(plan.Jobs) != 1 {
+		t.Fatalf("expected a single job, got:\n%s", plan)
+	}
+	job := plan.Jobs[0]
+	if job.Contiguous() || len(job.Spans) != 2 {
+		t.Fatalf("expected a multi job with two spans, got:\n%s", plan)
+	}
+	if job.Spans[0].Start != 2 || job.Spans[0].Size != 10 || len(job.Spans[0].Addrs) != 3 {
+		t.Errorf("expected V2..V11 to cover three addresses, got:\n%s", plan)
+	}
+	if job.Spans[1].Start != 800 || job.Spans[1].Size != 1 {
+		t.Errorf("expected V800 to be read on its own, got:\n%s", plan)
+	}
+}
+
+func TestReadPlanSplitting(t *testing.T) {
+	var addrs []gos7logo.VmAddr
+	for i := 0; i < 45; i++ {
+		addrs = append(addrs, gos7logo.NewVmAddr(gos7logo.Word, uint32(i*20), 0))
+	}
+	plan := gos7logo.NewReadPlan(addrs, 240)
+	total := 0
+	for _, job := range plan.Jobs {
+		if len(job.Spans) > 20 {
+			t.Errorf("job exceeds 20 items: %d", len(job.Spans))
+		}
+		total += len(job.Spans)
+	}
+	if total != 45 || len(plan.Jobs) != 3 {
+		t.Errorf("expected 45 items in 3 jobs, got:\n%s", plan)
+	}
+
+	plan = gos7logo.NewReadPlan(addrs, 120)
+	for _, job := range plan.Jobs {
+		if 19+12*len(job.Spans) > 120 {
+			t.Errorf("job request exceeds the PDU: %d items", len(job.Spans))
+		}
+	}
+}
+
+func TestWritePlanFarAddresses(t *testing.T) {
+	args := []gos7logo.VmAddrValue{
+		{VmAddr: gos7logo.NewVmAddr(gos7logo.Byte, 0, 0), Value: 1},
+		{VmAddr: gos7logo.NewVmAddr(gos7logo.Byte, 800, 0), Value: 2},
+	}
+	plan := gos7logo.NewWritePlan(args, 240)
+	for _, job := range plan.Jobs {
+		for _, span := range job.Spans {
+			if span.Size != 1 {
+				t.Errorf("expected only single bytes to be written, got:\n%s", plan)
+			}
+		}
+	}
+}
+
+func TestWritePlanBits(t *testing.T) {
+	args := []gos7logo.VmAddrValue{
+		{VmAddr: gos7logo.NewVmAddr(gos7logo.Bit, 3, 2), Value: 1},
+		{VmAddr: gos7logo.NewVmAddr(gos7logo.Byte, 10, 0), Value: 0xff},
+		{VmAddr: gos7logo.NewVmAddr(gos7logo.Bit, 10, 0), Value: 0},
+		{VmAddr: gos7logo.NewVmAddr(gos7logo.Byte, 11, 0), Value: 1},
+	}
+	plan := gos7logo.NewWritePlan(args, 240)
+	if len(plan.Jobs) != 1 || len(plan.Jobs[0].Spans) != 2 {
+		t.Fatalf("expected one job with a bit and a byte span, got:\n%s", plan)
+	}
+	bit, bytes := plan.Jobs[0].Spans[0], plan.Jobs[0].Spans[1]
+	if bit.Type != gos7logo.Bit || bit.Start != 3 || bit.Bit != 2 {
+		t.Errorf("expected a bit span for V3.2, got %s", bit)
+	}
+	if bytes.Type != gos7logo.Byte || bytes.Start != 10 || bytes.Size != 2 || len(bytes.Addrs) != 3 {
+		t.Errorf("expected V10..V11 to carry the folded bit, got %s %v", bytes, bytes.Addrs)
+	}
+}
@@ -0,0 +1,149 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+	"github.com/axon-expert/gos7-logo-client/logosim"
+)
+
+// pollClient serves ReadMany from a map and records the batch sizes.
+type pollClient struct {
+	gos7logo.Client
+	err     error
+	values  map[uint32]uint32
+	batches []int
+	mu      sync.Mutex
+}
+
+func (c *pollClient) ReadManyContext(ctx context.Context, addrs ...gos7logo.VmAddr) ([]gos7logo.VmAddrResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.batches = append(c.batches, len(addrs))
+	if c.err != nil {
+		return nil, c.err
+	}
+	results := make([]gos7logo.VmAddrResult, len(addrs))
+	for i, addr := range addrs {
+		results[i] = gos7logo.VmAddrResult{VmAddr: addr, Sample: gos7logo.Sample{Time: time.Now(), Value: c.values[addr.Byte]}}
+	}
+	return results, nil
+}
+
+func (c *pollClient) set(byte uint32, value uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[byte] = value
+}
+
+func TestPollerChanges(t *testing.T) {
+	c := &pollClient{values: map[uint32]uint32{10: 1, 20: 2}}
+	poller := gos7logo.NewPoller(c)
+	defer poller.Close()
+
+	_, events, err := poller.SubscribeChan(gos7logo.NewVmAddr(gos7logo.Byte, 10, 0), 5*time.Millisecond, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := poller.Subscribe(gos7logo.NewVmAddr(gos7logo.Byte, 20, 0), 5*time.Millisecond, func(gos7logo.ChangeEvent) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	first := <-events
+	if !first.First || first.New != 1 {
+		t.Fatalf("expected initial value 1, got %+v", first)
+	}
+	c.set(10, 7)
+	select {
+	case ev := <-events:
+		if ev.First || ev.Old != 1 || ev.New != 7 {
+			t.Errorf("expected change 1 -> 7, got %+v", ev)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no change event")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n := c.batches[len(c.batches)-1]; n != 2 {
+		t.Errorf("expected subscriptions of equal interval to share a batch, got %v", c.batches)
+	}
+}
+
+func TestPollerStaleQuality(t *testing.T) {
+	c := &pollClient{values: map[uint32]uint32{10: 3}}
+	poller := gos7logo.NewPoller(c)
+	defer poller.Close()
+
+	_, events, err := poller.SubscribeChan(gos7logo.NewVmAddr(gos7logo.Byte, 10, 0), 5*time.Millisecond, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := <-events
+	if first.Quality != gos7logo.QualityGood {
+		t.Fatalf("expected good initial sample, got %v", first.Quality)
+	}
+
+	c.mu.Lock()
+	c.err = errors.New("connection reset")
+	c.mu.Unlock()
+	stale := <-events
+	if stale.Err == nil || stale.Quality != gos7logo.QualityStale || stale.New != 3 || !stale.Time.Equal(first.Time) {
+		t.Errorf("expected stale sample of the last value, got %+v", stale)
+	}
+
+	c.mu.Lock()
+	c.err = nil
+	c.mu.Unlock()
+	if recovered := <-events; recovered.Quality != gos7logo.QualityGood || recovered.Err != nil {
+		t.Errorf("expected good sample after recovery, got %+v", recovered)
+	}
+}
+
+// the poller restores the connection of a client without reconnect policy
+func TestPollerRestoresConnection(t *testing.T) {
+	sim := startSim(t, logosim.Config{})
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()}, gos7logo.WithIdleTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+	poller := gos7logo.NewPoller(c)
+	defer poller.Close()
+	sub, events, err := poller.SubscribeChan(gos7logo.NewVmAddr(gos7logo.Byte, 5, 0), 20*time.Millisecond, 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	next := func() gos7logo.ChangeEvent {
+		t.Helper()
+		select {
+		case ev := <-events:
+			return ev
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected an event")
+		}
+		return gos7logo.ChangeEvent{}
+	}
+	if ev := next(); !ev.First || ev.Err != nil {
+		t.Fatalf("expected first value, got %+v", ev)
+	}
+
+	sim.DropConnections()
+	if err := sim.WriteVM(5, []byte{7}); err != nil {
+		t.Fatal(err)
+	}
+	if ev := next(); ev.Err == nil || ev.Quality != gos7logo.QualityStale {
+		t.Fatalf("expected stale error event after the drop, got %+v", ev)
+	}
+	if ev := next(); ev.Err != nil || ev.New != 7 {
+		t.Fatalf("expected 7 after the poller reconnected, got %+v", ev)
+	}
+	if c.Health().State != gos7logo.StateConnected {
+		t.Errorf("expected connected client, got %s", c.Health().State)
+	}
+}
@@ -0,0 +1,110 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+	"github.com/axon-expert/gos7-logo-client/logosim"
+)
+
+func TestReconnectBackoff(t *testing.T) {
+	policy := gos7logo.ReconnectPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: 100 * time.Millisecond,
+		MaxBackoff:     300 * time.Millisecond,
+	}
+	want := []time.Duration{0, 100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+	for attempt, w := range want {
+		if got := policy.Backoff(attempt); got != w {
+			t.Errorf("attempt %d: expected %s, got %s", attempt, w, got)
+		}
+	}
+
+	policy.Jitter = 0.5
+	for range 100 {
+		if got := policy.Backoff(2); got < 100*time.Millisecond || got > 300*time.Millisecond {
+			t.Fatalf("expected jittered backoff within 100ms..300ms, got %s", got)
+		}
+	}
+}
+
+func TestReconnectAfterIdleClose(t *testing.T) {
+	sim := startSim(t, logosim.Config{})
+	if err := sim.WriteVM(7, []byte{3}); err != nil {
+		t.Fatal(err)
+	}
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()},
+		gos7logo.WithIdleTimeout(30*time.Millisecond),
+		gos7logo.WithReconnect(gos7logo.DefaultReconnectPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+	time.Sleep(100 * time.Millisecond)
+	if c.Health().State != gos7logo.StateDisconnected {
+		t.Fatalf("expected idle connection to be closed, got %s", c.Health().State)
+	}
+
+	var trace gos7logo.RetryTrace
+	v, err := c.ReadContext(gos7logo.WithRetryTrace(context.Background(), &trace), gos7logo.NewVmAddr(gos7logo.Byte, 7, 0))
+	if err != nil || v != 3 {
+		t.Fatalf("expected 3 after reconnect, got %d %v", v, err)
+	}
+	// reconnected before sending, nothing had to be resent
+	if trace.Reconnects != 1 || trace.Retried {
+		t.Errorf("expected one reconnect without resend, got %+v", trace)
+	}
+}
+
+func TestReconnectResendAfterBrokenSocket(t *testing.T) {
+	sim := startSim(t, logosim.Config{})
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()},
+		gos7logo.WithReconnect(gos7logo.DefaultReconnectPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	// the PLC was power-cycled: the socket is reset under the client
+	sim.DropConnections()
+	var trace gos7logo.RetryTrace
+	ctx := gos7logo.WithRetryTrace(context.Background(), &trace)
+	if err := c.WriteContext(ctx, gos7logo.NewVmAddr(gos7logo.Word, 8, 0), 0x0102); err != nil {
+		t.Fatalf("expected write to be resent, got %v", err)
+	}
+	if trace.Reconnects != 1 || !trace.Retried {
+		t.Errorf("expected one reconnect and a resend, got %+v", trace)
+	}
+	if b, _ := sim.ReadVM(8, 2); b[0] != 1 || b[1] != 2 {
+		t.Errorf("expected VW8 written after reconnect, got % x", b)
+	}
+	if c.Health().State != gos7logo.StateConnected {
+		t.Errorf("expected connected after reconnect, got %s", c.Health().State)
+	}
+}
+
+func TestReconnectGivesUp(t *testing.T) {
+	sim := startSim(t, logosim.Config{})
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()},
+		gos7logo.WithReconnect(gos7logo.ReconnectPolicy{MaxAttempts: 3, InitialBackoff: 20 * time.Millisecond, MaxBackoff: 20 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+	_ = sim.Close()
+
+	var trace gos7logo.RetryTrace
+	start := time.Now()
+	if _, err := c.ReadContext(gos7logo.WithRetryTrace(context.Background(), &trace), gos7logo.NewVmAddr(gos7logo.Byte, 0, 0)); err == nil {
+		t.Fatal("expected error with the PLC gone")
+	}
+	// attempts 2 and 3 wait for the backoff
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected backoff between attempts, took %s", elapsed)
+	}
+	if trace.Reconnects != 0 {
+		t.Errorf("expected no successful reconnect, got %+v", trace)
+	}
+}
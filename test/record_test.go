@@ -0,0 +1,102 @@
+package test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+	gos7patch "github.com/axon-expert/gos7-logo-client/gos7-patch"
+	"github.com/axon-expert/gos7-logo-client/logosim"
+)
+
+func TestRecordReplay(t *testing.T) {
+	sim := startSim(t, logosim.Config{PDULength: 64})
+	if err := sim.WriteVM(0, []byte{0x12, 0x34, 0, 7}); err != nil {
+		t.Fatal(err)
+	}
+	var recording bytes.Buffer
+	var recorder *gos7patch.RecordingTransporter
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()},
+		gos7logo.WithTransportWrapper(func(inner gos7logo.Transporter) gos7logo.Transporter {
+			recorder = gos7patch.NewRecordingTransporter(inner, &recording)
+			return recorder
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the session under test: a multi-read, a write and a read back
+	addrs := []gos7logo.VmAddr{gos7logo.NewVmAddr(gos7logo.Word, 0, 0), gos7logo.NewVmAddr(gos7logo.Word, 200, 0)}
+	session := func(c gos7logo.Client) (word, back uint32, err error) {
+		results, err := c.ReadMany(addrs...)
+		if err != nil {
+			return 0, 0, err
+		}
+		if err := c.Write(gos7logo.NewVmAddr(gos7logo.Byte, 3, 0), 8); err != nil {
+			return 0, 0, err
+		}
+		back, err = c.Read(gos7logo.NewVmAddr(gos7logo.Byte, 3, 0))
+		return results[0].Value, back, err
+	}
+	word, back, err := session(c)
+	_ = c.Disconnect()
+	if err != nil || word != 0x1234 || back != 8 {
+		t.Fatalf("expected 0x1234 and 8, got %#x %d %v", word, back, err)
+	}
+	if recorder.Err() != nil {
+		t.Fatal(recorder.Err())
+	}
+	sim.Close()
+
+	exchanges, err := gos7patch.ReadRecording(&recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(exchanges) != 3 || exchanges[0].PDULength != 64 || exchanges[0].Time.IsZero() {
+		t.Fatalf("expected 3 timestamped exchanges at PDU 64, got %+v", exchanges)
+	}
+	replay := gos7patch.NewReplayTransporter(exchanges)
+	c, err = gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{}, gos7logo.WithTransport(replay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+	word, back, err = session(c)
+	if err != nil || word != 0x1234 || back != 8 {
+		t.Errorf("expected replay to give 0x1234 and 8, got %#x %d %v", word, back, err)
+	}
+	if len(replay.Remaining()) != 0 {
+		t.Errorf("expected all exchanges replayed, %d left", len(replay.Remaining()))
+	}
+	if _, err := c.Read(gos7logo.NewVmAddr(gos7logo.Byte, 3, 0)); !errors.Is(err, gos7patch.ErrNotRecorded) {
+		t.Errorf("expected not recorded error, got %v", err)
+	}
+}
+
+func TestReplayMatching(t *testing.T) {
+	request := []byte{3, 0, 0, 13, 2, 240, 128, 50, 1, 0, 0, 5, 0x0A}
+	recording := `{"time":"2026-10-18T07:00:00Z","request":"0300000d02f08032010000050a","response":"0300001302f080320300000500000000000000","durationUs":900,"pduLength":240}
+{"time":"2026-10-18T07:00:01Z","request":"0300000d02f08032010000050a","error":"read tcp: connection reset by peer","durationUs":5}
+`
+	exchanges, err := gos7patch.ReadRecording(strings.NewReader(recording))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replay := gos7patch.NewReplayTransporter(exchanges)
+	// a different PDU reference still matches and is answered with it
+	request[11], request[12] = 0x07, 0x01
+	response, err := replay.Send(request)
+	if err != nil || response[11] != 0x07 || response[12] != 0x01 {
+		t.Errorf("expected response with PDU reference 07 01, got % x %v", response, err)
+	}
+	if _, err := replay.Send(request); err == nil || err.Error() != "read tcp: connection reset by peer" {
+		t.Errorf("expected recorded error, got %v", err)
+	}
+	if _, err := replay.Send(request); !errors.Is(err, gos7patch.ErrNotRecorded) {
+		t.Errorf("expected not recorded error, got %v", err)
+	}
+	if _, err := gos7patch.ReadRecording(strings.NewReader(`{"request":"zz"}`)); err == nil {
+		t.Errorf("expected error for invalid hex")
+	}
+}
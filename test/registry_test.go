@@ -0,0 +1,85 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+const tagsYAML = `
+tags:
+  - name: boiler_temp
+    addr: VW12
+    type: int16
+    description: Boiler temperature
+    readOnly: true
+    scaling: {rawMin: 0, rawMax: 1000, engMin: -50, engMax: 150}
+  - name: setpoint
+    addr: VW14
+  - name: pump_on
+    addr: V10.0
+`
+
+// registryClient keeps written words in memory.
+type registryClient struct {
+	gos7logo.Client
+	words map[uint32]uint32
+}
+
+func (c *registryClient) ReadContext(ctx context.Context, addr gos7logo.VmAddr) (uint32, error) {
+	return c.words[addr.Byte], nil
+}
+
+func (c *registryClient) ReadInt16Context(ctx context.Context, addr gos7logo.VmAddr) (int16, error) {
+	return int16(c.words[addr.Byte]), nil
+}
+
+func (c *registryClient) WriteContext(ctx context.Context, addr gos7logo.VmAddr, value uint32) error {
+	c.words[addr.Byte] = value
+	return nil
+}
+
+func TestRegistry(t *testing.T) {
+	registry, err := gos7logo.ParseRegistryYAML([]byte(tagsYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := registry.Addr("boiler_temp")
+	if err != nil || addr.Type != gos7logo.Int16 || addr.Byte != 12 {
+		t.Fatalf("expected boiler_temp at VW12 as int16, got %+v %v", addr, err)
+	}
+
+	c := &registryClient{words: map[uint32]uint32{12: 500}}
+	ctx := context.Background()
+	if temp, err := registry.Read(ctx, c, "boiler_temp"); err != nil || temp != 50 {
+		t.Errorf("expected 50 °C, got %v %v", temp, err)
+	}
+	if err := registry.Write(ctx, c, "boiler_temp", 20); !errors.Is(err, gos7logo.ErrReadOnlyTag) {
+		t.Errorf("expected read-only error, got %v", err)
+	}
+	if err := registry.Write(ctx, c, "setpoint", 70000); err == nil {
+		t.Errorf("expected out of range error for word")
+	}
+	if err := registry.Write(ctx, c, "setpoint", 42); err != nil || c.words[14] != 42 {
+		t.Errorf("expected setpoint 42, got %v %v", c.words[14], err)
+	}
+	if _, err := registry.Read(ctx, c, "missing"); !errors.Is(err, gos7logo.ErrUnknownTag) {
+		t.Errorf("expected unknown tag error, got %v", err)
+	}
+}
+
+func TestRegistryInvalid(t *testing.T) {
+	cases := []string{
+		`{"tags": [{"name": "a", "addr": "X1"}]}`,
+		`{"tags": [{"name": "a", "addr": "VW2", "type": "float32"}]}`,
+		`{"tags": [{"name": "a", "addr": "V2"}, {"name": "a", "addr": "V3"}]}`,
+		`{"tags": [{"name": "a", "addr": "VW2", "scaling": {"rawMin": 1, "rawMax": 1}}]}`,
+	}
+	for _, data := range cases {
+		if _, err := gos7logo.ParseRegistryJSON([]byte(data)); err == nil {
+			t.Errorf("expected error for %s", data)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package test
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+func TestScalingLinear(t *testing.T) {
+	s := gos7logo.LinearScaling(0, 1000, -50, 150, "°C")
+	cases := []struct {
+		raw, eng float64
+	}{
+		{0, -50},
+		{250, 0},
+		{1000, 150},
+		{1100, 170},
+	}
+	for _, c := range cases {
+		if eng := s.ToEng(c.raw); math.Abs(eng-c.eng) > 1e-9 {
+			t.Errorf("expected %v for raw %v, got %v", c.eng, c.raw, eng)
+		}
+	}
+	s.Clamp = true
+	if eng := s.ToEng(1100); eng != 150 {
+		t.Errorf("expected clamped 150, got %v", eng)
+	}
+	if raw, err := s.ToRaw(100); err != nil || raw != 750 {
+		t.Errorf("expected raw 750, got %v %v", raw, err)
+	}
+	if _, err := s.ToRaw(151); err == nil {
+		t.Errorf("expected out of range error")
+	}
+}
+
+func TestScalingPiecewise(t *testing.T) {
+	s := gos7logo.Scaling{
+		Unit: "bar",
+		Points: []gos7logo.ScalePoint{
+			{Raw: 0, Eng: 10},
+			{Raw: 500, Eng: 4},
+			{Raw: 1000, Eng: 0},
+		},
+	}
+	if eng := s.ToEng(250); eng != 7 {
+		t.Errorf("expected 7 bar, got %v", eng)
+	}
+	if eng := s.ToEng(750); eng != 2 {
+		t.Errorf("expected 2 bar, got %v", eng)
+	}
+	if raw, err := s.ToRaw(2); err != nil || raw != 750 {
+		t.Errorf("expected raw 750, got %v %v", raw, err)
+	}
+	if raw, err := s.ToRaw(7); err != nil || raw != 250 {
+		t.Errorf("expected raw 250, got %v %v", raw, err)
+	}
+}
+
+func TestScaledAddr(t *testing.T) {
+	if _, err := gos7logo.NewScaledAddr(gos7logo.NewVmAddr(gos7logo.Bit, 1, 0), gos7logo.LinearScaling(0, 1, 0, 1, "")); err == nil {
+		t.Errorf("expected error for bit address")
+	}
+	if _, err := gos7logo.NewScaledAddr(gos7logo.NewVmAddr(gos7logo.Word, 1, 0), gos7logo.LinearScaling(0, 0, 0, 1, "")); err == nil {
+		t.Errorf("expected error for empty raw range")
+	}
+
+	addr, err := gos7logo.NewScaledAddr(gos7logo.NewVmAddr(gos7logo.Word, 4, 0), gos7logo.LinearScaling(0, 1000, 0, 10, "bar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &registryClient{words: map[uint32]uint32{}}
+	ctx := context.Background()
+	if err := addr.Write(ctx, c, 2.5); err != nil || c.words[4] != 250 {
+		t.Errorf("expected raw 250, got %v %v", c.words[4], err)
+	}
+	if v, err := addr.Read(ctx, c); err != nil || v != 2.5 {
+		t.Errorf("expected 2.5 bar, got %v %v", v, err)
+	}
+	if err := addr.Write(ctx, c, 11); err == nil {
+		t.Errorf("expected out of range error")
+	}
+}
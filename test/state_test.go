@@ -0,0 +1,79 @@
+package test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+	gos7patch "github.com/axon-expert/gos7-logo-client/gos7-patch"
+	"github.com/axon-expert/gos7-logo-client/logosim"
+)
+
+func TestConnStateOnFailedConnect(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	handler := gos7patch.NewTCPClientHandler(addr, 0, 1)
+	var changes []gos7patch.StateChange
+	unsubscribe := handler.Subscribe(func(change gos7patch.StateChange) {
+		changes = append(changes, change)
+	})
+	if err := handler.Connect(); err == nil {
+		t.Fatal("expected connect to fail")
+	}
+	unsubscribe()
+	handler.Close()
+
+	if len(changes) != 2 || changes[0].To != gos7patch.StateConnecting || changes[1].To != gos7patch.StateDisconnected {
+		t.Fatalf("expected connecting -> disconnected, got %v", changes)
+	}
+	health := handler.Health()
+	if health.State != gos7patch.StateClosed || health.LastError == nil {
+		t.Errorf("expected closed state with last error, got %+v", health)
+	}
+}
+
+// run with -race: Health is polled while the transport reconnects
+func TestHealthDuringReconnect(t *testing.T) {
+	sim := startSim(t, logosim.Config{PDULength: 200})
+	c, err := gos7logo.NewClientWithOpt(gos7logo.ConnectOpt{Addr: sim.Addr()},
+		gos7logo.WithReconnect(gos7logo.DefaultReconnectPolicy()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Disconnect()
+
+	done := make(chan struct{})
+	polled := make(chan int)
+	go func() {
+		n := 0
+		for {
+			select {
+			case <-done:
+				polled <- n
+				return
+			default:
+				if h := c.Health(); h.PDULength != 200 {
+					t.Errorf("expected PDU length 200, got %d", h.PDULength)
+				}
+				n++
+				time.Sleep(100 * time.Microsecond)
+			}
+		}
+	}()
+	for range 20 {
+		sim.DropConnections()
+		if _, err := c.Read(gos7logo.NewVmAddr(gos7logo.Byte, 0, 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	if <-polled == 0 {
+		t.Errorf("expected Health to be polled during the reconnects")
+	}
+}
@@ -0,0 +1,46 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	gos7logo "github.com/axon-expert/gos7-logo-client"
+)
+
+func TestNewTagTypeCheck(t *testing.T) {
+	vw := gos7logo.NewVmAddr(gos7logo.Word, 12, 0)
+	if _, err := gos7logo.NewTag[int16](client, vw); err != nil {
+		t.Errorf("unexpected error for int16 tag on word: %s", err)
+	}
+	if _, err := gos7logo.NewTag[float32](client, vw); err == nil {
+		t.Errorf("expected error for float32 tag on word")
+	}
+	if _, err := gos7logo.NewTag[bool](client, gos7logo.NewVmAddr(gos7logo.Byte, 3, 0)); err == nil {
+		t.Errorf("expected error for bool tag on byte")
+	}
+}
+
+func TestTagSetGet(t *testing.T) {
+	ctx := context.Background()
+	bit, err := gos7logo.NewTag[bool](client, gos7logo.NewVmAddr(gos7logo.Bit, 2, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bit.Set(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := bit.Get(ctx); err != nil || !v {
+		t.Errorf("expected true, got %v (%v)", v, err)
+	}
+
+	temp, err := gos7logo.NewTag[int16](client, gos7logo.NewVmAddr(gos7logo.Word, 52, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := temp.Set(ctx, -40); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := temp.Get(ctx); err != nil || v != -40 {
+		t.Errorf("expected -40, got %d (%v)", v, err)
+	}
+}
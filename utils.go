@@ -1,5 +1,5 @@
 package gos7logo
 
-func compareVmAddrByte(a, b VmAddrValue) int {
-	return int(a.VmAddr.Byte) - int(b.VmAddr.Byte)
+func compareVmAddrByte(a, b vmAddr) int {
+	return int(a.Byte) - int(b.Byte)
 }